@@ -0,0 +1,107 @@
+package bls
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBackend is the name of the backend used when none was selected, as long as
+// it is registered. Other backends are only used once selected with UseBackend, so
+// the pure Go backend, which is not side-channel safe, is never used implicitly.
+const DefaultBackend = "herumi"
+
+// Backend implements the BLS signature scheme on top of a BLS12-381 library. Keys and
+// signatures created by a backend can only be used with other values of that backend.
+type Backend interface {
+	SecretKeyFromBytes(privKey []byte) (SecretKey, error)
+	PublicKeyFromBytes(pubKey []byte) (PublicKey, error)
+	SignatureFromBytes(sig []byte) (Signature, error)
+	RandKey() (SecretKey, error)
+	AggregatePublicKeys(pubs [][]byte) (PublicKey, error)
+	AggregateSignatures(sigs []Signature) Signature
+	VerifyMultipleSignatures(sigs []Signature, msgs [][32]byte, pubKeys []PublicKey) (bool, error)
+}
+
+var (
+	backendsLock sync.RWMutex
+	backends     = make(map[string]Backend)
+	selected     string
+)
+
+// RegisterBackend makes a backend available under the given name. Backend packages
+// register themselves when imported, see the backends package. It panics if a backend
+// is already registered under the name.
+func RegisterBackend(name string, backend Backend) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if _, ok := backends[name]; ok {
+		panic("bls: backend registered twice: " + name)
+	}
+	backends[name] = backend
+}
+
+// UseBackend selects the registered backend of the given name for the functions of
+// this package. It must be called before any key or signature is created, as values
+// of different backends cannot be mixed.
+func UseBackend(name string) error {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if _, ok := backends[name]; !ok {
+		return errors.Wrapf(ErrUnknownBackend, "%s, registered backends are %v", name, registeredBackends())
+	}
+	selected = name
+	return nil
+}
+
+// Backends returns the names of the registered backends, sorted.
+func Backends() []string {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+	return registeredBackends()
+}
+
+// BackendName returns the name of the backend in use, empty if none was selected and
+// the default backend is not registered.
+func BackendName() string {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+	return currentBackendName()
+}
+
+func registeredBackends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func currentBackendName() string {
+	if selected != "" {
+		return selected
+	}
+	if _, ok := backends[DefaultBackend]; ok {
+		return DefaultBackend
+	}
+	return ""
+}
+
+// currentBackend returns the backend in use. It panics if there is none, as nothing
+// can be signed or verified then.
+func currentBackend() Backend {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+	name := currentBackendName()
+	if name == "" {
+		if len(backends) == 0 {
+			panic("bls: no backend registered, import github.com/atif-konasl/eth-research/bls/backends")
+		}
+		panic("bls: default backend " + DefaultBackend + " is not registered, select one of " +
+			strings.Join(registeredBackends(), ", ") + " with UseBackend")
+	}
+	return backends[name]
+}
@@ -0,0 +1,19 @@
+// Package backends registers the BLS backends available to the build with the bls
+// package, which is then used through its SecretKeyFromBytes, PublicKeyFromBytes,
+// SignatureFromBytes and RandKey functions:
+//
+//	import (
+//		"github.com/atif-konasl/eth-research/bls"
+//		_ "github.com/atif-konasl/eth-research/bls/backends"
+//	)
+//
+// The pure Go backend is always registered. The herumi backend needs cgo, and is used
+// by default when it is available. Another backend is selected at run time with
+// bls.UseBackend. Building with CGO_ENABLED=0 leaves only the pure Go backend, which is
+// not side-channel safe and must then be selected explicitly.
+package backends
+
+import (
+	// Register the pure Go backend.
+	_ "github.com/atif-konasl/eth-research/bls/purego"
+)
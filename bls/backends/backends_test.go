@@ -0,0 +1,222 @@
+package backends_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	_ "github.com/atif-konasl/eth-research/bls/backends"
+	"github.com/atif-konasl/eth-research/bls/purego"
+	"github.com/atif-konasl/eth-research/testutil/assert"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+// signVector is a signature of the Ethereum 2.0 BLS test vectors, which every backend
+// must reproduce.
+type signVector struct {
+	secretKey string
+	publicKey string
+	message   string
+	signature string
+}
+
+var signVectors = []signVector{
+	{
+		secretKey: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		publicKey: "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		message:   "0000000000000000000000000000000000000000000000000000000000000000",
+		signature: "b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55",
+	},
+	{
+		secretKey: "263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3",
+		publicKey: "a491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+		message:   "5656565656565656565656565656565656565656565656565656565656565656",
+		signature: "882730e5d03f6b42c3abc26d3372625034e1d871b65a8a6b900a56dae22da98abbe1b68f85e49fe7652a55ec3d0591c20767677e33e5cbb1207315c41a9ac03be39c2e7668edc043d6cb1d9fd93033caa8a1c5b0e84bedaeb6c64972503a43eb",
+	},
+	{
+		secretKey: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		publicKey: "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		message:   "0000000000000000000000000000000000000000000000000000000000000000",
+		signature: "b23c46be3a001c63ca711f87a005c200cc550b9429d5f4eb38d74322144f1b63926da3388979e5321012fb1a0526bcd100b5ef5fe72628ce4cd5e904aeaa3279527843fae5ca9ca675f4f51ed8f83bbf7155da9ecc9663100a885d5dc6df96d9",
+	},
+	{
+		secretKey: "47b8192d77bf871b62e87859d653922725724a5c031afeabc60bcef5ff665138",
+		publicKey: "b301803f8b5ac4a1133581fc676dfedc60d891dd5fa99028805e5ea5b08d3491af75d0707adab3b70c6a6a580217bf81",
+		message:   "abababababababababababababababababababababababababababababababab",
+		signature: "9674e2228034527f4c083206032b020310face156d4a4685e2fcaec2f6f3665aa635d90347b6ce124eb879266b1e801d185de36a0a289b85e9039662634f2eea1e02e670bc7ab849d006a70b2f93b84597558a05b879c8d445f387a5d5b653df",
+	},
+	{
+		secretKey: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		publicKey: "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		message:   "5656565656565656565656565656565656565656565656565656565656565656",
+		signature: "a4efa926610b8bd1c8330c918b7a5e9bf374e53435ef8b7ec186abf62e1b1f65aeaaeb365677ac1d1172a1f5b44b4e6d022c252c58486c0a759fbdc7de15a756acc4d343064035667a594b4c2a6f0b0b421975977f297dba63ee2f63ffe47bb6",
+	},
+	{
+		secretKey: "328388aff0d4a5b7dc9205abd374e7e98f3cd9f3418edb4eafda5fb16473d216",
+		publicKey: "b53d21a4cfd562c469cc81514d4ce5a6b577d8403d32a394dc265dd190b47fa9f829fdd7963afdf972e5e77854051f6f",
+		message:   "abababababababababababababababababababababababababababababababab",
+		signature: "ae82747ddeefe4fd64cf9cedb9b04ae3e8a43420cd255e3c7cd06a8d88b7c7f8638543719981c5d16fa3527c468c25f0026704a6951bde891360c7e8d12ddee0559004ccdbe6046b55bae1b257ee97f7cdb955773d7cf29adf3ccbb9975e4eb9",
+	},
+}
+
+// invalidPublicKeys and invalidSignatures must be rejected by every backend.
+var (
+	invalidPublicKeys = map[string]string{
+		"Infinity":      "c00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"NotOnCurve":    "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001",
+		"NotInSubgroup": "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004",
+		"Uncompressed":  "1491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a",
+	}
+	invalidSignatures = map[string]string{
+		"NotOnCurve":    "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001",
+		"NotInSubgroup": "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002",
+	}
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// restoreBackend selects the backend in use again once the test is done. Without
+// default backend, the last selected backend remains in use.
+func restoreBackend(t *testing.T) {
+	backend := bls.BackendName()
+	t.Cleanup(func() {
+		if backend != "" {
+			require.NoError(t, bls.UseBackend(backend))
+		}
+	})
+}
+
+// forEachBackend runs the test with every registered backend selected in turn.
+func forEachBackend(t *testing.T, test func(t *testing.T)) {
+	restoreBackend(t)
+	for _, name := range bls.Backends() {
+		require.NoError(t, bls.UseBackend(name))
+		t.Run(name, test)
+	}
+}
+
+func TestDefaultBackend(t *testing.T) {
+	// The pure Go backend is not side-channel safe, so it is never used unless selected.
+	assert.NotEqual(t, purego.Name, bls.BackendName())
+	if name := bls.BackendName(); name != "" {
+		assert.Equal(t, bls.DefaultBackend, name)
+	}
+}
+
+func TestBackends_SignVectors(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		for _, v := range signVectors {
+			secretKey, err := bls.SecretKeyFromBytes(decodeHex(t, v.secretKey))
+			require.NoError(t, err)
+			assert.Equal(t, v.publicKey, hex.EncodeToString(secretKey.PublicKey().Marshal()))
+			signature := secretKey.Sign(decodeHex(t, v.message))
+			assert.Equal(t, v.signature, hex.EncodeToString(signature.Marshal()))
+
+			publicKey, err := bls.PublicKeyFromBytes(decodeHex(t, v.publicKey))
+			require.NoError(t, err)
+			decoded, err := bls.SignatureFromBytes(decodeHex(t, v.signature))
+			require.NoError(t, err)
+			assert.Equal(t, v.signature, decoded.HexString())
+			assert.Equal(t, true, decoded.Verify(publicKey, decodeHex(t, v.message)))
+			assert.Equal(t, false, decoded.Verify(publicKey, []byte("another message")))
+		}
+	})
+}
+
+func TestBackends_AggregateVectors(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		var pubKeys []bls.PublicKey
+		var sigs []bls.Signature
+		var msgs [][32]byte
+		for _, v := range signVectors {
+			publicKey, err := bls.PublicKeyFromBytes(decodeHex(t, v.publicKey))
+			require.NoError(t, err)
+			signature, err := bls.SignatureFromBytes(decodeHex(t, v.signature))
+			require.NoError(t, err)
+			var msg [32]byte
+			copy(msg[:], decodeHex(t, v.message))
+			pubKeys = append(pubKeys, publicKey)
+			sigs = append(sigs, signature)
+			msgs = append(msgs, msg)
+		}
+		aggregate := bls.AggregateSignatures(sigs)
+		assert.Equal(t, true, aggregate.AggregateVerify(pubKeys, msgs))
+		assert.Equal(t, false, aggregate.AggregateVerify(pubKeys[1:], msgs[1:]))
+		valid, err := bls.VerifyMultipleSignatures(sigs, msgs, pubKeys)
+		require.NoError(t, err)
+		assert.Equal(t, true, valid)
+		valid, err = bls.VerifyMultipleSignatures(sigs[1:], msgs[:len(msgs)-1], pubKeys[1:])
+		require.NoError(t, err)
+		assert.Equal(t, false, valid)
+
+		var sameMsgSigs []bls.Signature
+		for _, v := range signVectors {
+			secretKey, err := bls.SecretKeyFromBytes(decodeHex(t, v.secretKey))
+			require.NoError(t, err)
+			sameMsgSigs = append(sameMsgSigs, secretKey.Sign(msgs[0][:]))
+		}
+		aggregate = bls.AggregateSignatures(sameMsgSigs)
+		assert.Equal(t, true, aggregate.FastAggregateVerify(pubKeys, msgs[0]))
+		assert.Equal(t, false, aggregate.FastAggregateVerify(pubKeys, msgs[1]))
+	})
+}
+
+func TestBackends_InvalidEncodings(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		for name, pubKey := range invalidPublicKeys {
+			_, err := bls.PublicKeyFromBytes(decodeHex(t, pubKey))
+			assert.NotNil(t, err, name)
+		}
+		_, err := bls.PublicKeyFromBytes(decodeHex(t, invalidPublicKeys["Infinity"]))
+		assert.Equal(t, true, errors.Is(err, bls.ErrInfinitePubKey))
+		for name, sig := range invalidSignatures {
+			_, err := bls.SignatureFromBytes(decodeHex(t, sig))
+			assert.NotNil(t, err, name)
+		}
+		_, err = bls.SecretKeyFromBytes(make([]byte, 32))
+		assert.Equal(t, bls.ErrZeroKey, err)
+	})
+}
+
+// TestBackends_Interoperable checks that keys and signatures encoded by a backend are
+// accepted by every other backend.
+func TestBackends_Interoperable(t *testing.T) {
+	msg := []byte("slot info signing root")
+	forEachBackend(t, func(t *testing.T) {
+		secretKey, err := bls.RandKey()
+		require.NoError(t, err)
+		encodedKey, encodedPubKey := secretKey.Marshal(), secretKey.PublicKey().Marshal()
+		encodedSig := secretKey.Sign(msg).Marshal()
+		current := bls.BackendName()
+		for _, name := range bls.Backends() {
+			require.NoError(t, bls.UseBackend(name))
+			otherKey, err := bls.SecretKeyFromBytes(encodedKey)
+			require.NoError(t, err)
+			assert.DeepEqual(t, encodedPubKey, otherKey.PublicKey().Marshal(), name)
+			publicKey, err := bls.PublicKeyFromBytes(encodedPubKey)
+			require.NoError(t, err)
+			signature, err := bls.SignatureFromBytes(encodedSig)
+			require.NoError(t, err)
+			assert.Equal(t, true, signature.Verify(publicKey, msg), name)
+		}
+		require.NoError(t, bls.UseBackend(current))
+	})
+}
+
+func TestUseBackend(t *testing.T) {
+	assert.Equal(t, true, len(bls.Backends()) > 0)
+	err := bls.UseBackend("unknown")
+	assert.Equal(t, true, errors.Is(err, bls.ErrUnknownBackend))
+
+	restoreBackend(t)
+	require.NoError(t, bls.UseBackend(purego.Name))
+	secretKey, err := bls.RandKey()
+	require.NoError(t, err)
+	_, ok := secretKey.PublicKey().(*purego.PublicKey)
+	assert.Equal(t, true, ok)
+}
@@ -0,0 +1,8 @@
+// +build cgo
+
+package backends
+
+import (
+	// Register the herumi backend, which wraps a C library.
+	_ "github.com/atif-konasl/eth-research/bls/herumi"
+)
@@ -0,0 +1,36 @@
+package bls
+
+// SecretKeyFromBytes creates a BLS private key from a BigEndian byte slice.
+func SecretKeyFromBytes(privKey []byte) (SecretKey, error) {
+	return currentBackend().SecretKeyFromBytes(privKey)
+}
+
+// PublicKeyFromBytes creates a BLS public key from a BigEndian byte slice.
+func PublicKeyFromBytes(pubKey []byte) (PublicKey, error) {
+	return currentBackend().PublicKeyFromBytes(pubKey)
+}
+
+// SignatureFromBytes creates a BLS signature from a LittleEndian byte slice.
+func SignatureFromBytes(sig []byte) (Signature, error) {
+	return currentBackend().SignatureFromBytes(sig)
+}
+
+// RandKey creates a new private key using a random input.
+func RandKey() (SecretKey, error) {
+	return currentBackend().RandKey()
+}
+
+// AggregatePublicKeys aggregates the provided raw public keys into a single key.
+func AggregatePublicKeys(pubs [][]byte) (PublicKey, error) {
+	return currentBackend().AggregatePublicKeys(pubs)
+}
+
+// AggregateSignatures converts a list of signatures into a single, aggregated sig.
+func AggregateSignatures(sigs []Signature) Signature {
+	return currentBackend().AggregateSignatures(sigs)
+}
+
+// VerifyMultipleSignatures verifies multiple signatures for distinct messages securely.
+func VerifyMultipleSignatures(sigs []Signature, msgs [][32]byte, pubKeys []PublicKey) (bool, error) {
+	return currentBackend().VerifyMultipleSignatures(sigs, msgs, pubKeys)
+}
@@ -11,3 +11,6 @@ var ErrSecretUnmarshal = errors.New("could not unmarshal bytes into secret key")
 
 // ErrInfinitePubKey describes an error due to an infinite public key.
 var ErrInfinitePubKey = errors.New("received an infinite public key")
+
+// ErrUnknownBackend describes an error due to selecting a backend which is not registered.
+var ErrUnknownBackend = errors.New("unknown BLS backend")
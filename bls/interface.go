@@ -1,8 +1,9 @@
-// Package common provides the BLS interfaces that are implemented by the various BLS wrappers.
+// Package bls provides the BLS interfaces that are implemented by the various BLS wrappers,
+// and creates keys and signatures with the backend selected among the registered ones.
 //
-// This package should not be used by downstream consumers. These interfaces are re-exporter by
-// github.com/prysmaticlabs/prysm/shared/bls. This package exists to prevent an import circular
-// dependency.
+// The backends import this package for its interfaces, so they register themselves
+// instead of being imported here, which would be an import cycle. Import
+// github.com/atif-konasl/eth-research/bls/backends to register every available backend.
 package bls
 
 // SecretKey represents a BLS secret or private key.
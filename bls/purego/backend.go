@@ -0,0 +1,43 @@
+package purego
+
+import common "github.com/atif-konasl/eth-research/bls"
+
+// Name the pure Go backend is registered under with the bls package.
+const Name = "purego"
+
+func init() {
+	common.RegisterBackend(Name, backend{})
+}
+
+// backend exposes the functions of this package as a bls.Backend.
+type backend struct{}
+
+func (backend) SecretKeyFromBytes(privKey []byte) (common.SecretKey, error) {
+	return SecretKeyFromBytes(privKey)
+}
+
+func (backend) PublicKeyFromBytes(pubKey []byte) (common.PublicKey, error) {
+	return PublicKeyFromBytes(pubKey)
+}
+
+func (backend) SignatureFromBytes(sig []byte) (common.Signature, error) {
+	return SignatureFromBytes(sig)
+}
+
+func (backend) RandKey() (common.SecretKey, error) {
+	return RandKey()
+}
+
+func (backend) AggregatePublicKeys(pubs [][]byte) (common.PublicKey, error) {
+	return AggregatePublicKeys(pubs)
+}
+
+func (backend) AggregateSignatures(sigs []common.Signature) common.Signature {
+	return AggregateSignatures(sigs)
+}
+
+func (backend) VerifyMultipleSignatures(
+	sigs []common.Signature, msgs [][32]byte, pubKeys []common.PublicKey,
+) (bool, error) {
+	return VerifyMultipleSignatures(sigs, msgs, pubKeys)
+}
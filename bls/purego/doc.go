@@ -0,0 +1,15 @@
+// Package purego implements the BLS signature scheme used by Ethereum 2.0 in pure Go,
+// on top of the BLS12-381 arithmetic of go-ethereum. It does not need cgo, and creates
+// the same keys and signatures as the herumi package, in the same encodings.
+//
+// Public keys and signatures are encoded as compressed points in the ZCash format, and
+// messages are hashed to G2 with the proof of possession ciphersuite of the IETF
+// hash-to-curve specification.
+//
+// This backend is not side-channel safe. Secret keys are multiplied with the scalar
+// multiplication of go-ethereum, a double-and-add loop over a big.Int which branches on
+// every bit of the key, so the time taken to derive a public key or to sign leaks the
+// secret key to an observer able to measure it. It is therefore never used unless
+// selected with bls.UseBackend, and the herumi backend should be preferred wherever
+// cgo is available.
+package purego
@@ -0,0 +1,224 @@
+package purego
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// Flags in the three most significant bits of a compressed point, in the ZCash format.
+const (
+	compressedFlag = 0x80
+	infinityFlag   = 0x40
+	signFlag       = 0x20
+	flagsMask      = compressedFlag | infinityFlag | signFlag
+)
+
+var (
+	// fieldModulus p of the base field of BLS12-381.
+	fieldModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+	// halfFieldModulus is (p-1)/2. The sign flag is set for y coordinates above it.
+	halfFieldModulus = new(big.Int).Rsh(fieldModulus, 1)
+	// curveOrder r of G1 and G2, the modulus of secret keys.
+	curveOrder = bls12381.NewG1().Q()
+	// twistB is the b coefficient 4(1+i) of the curve of G2, y^2 = x^3 + b.
+	twistB = [2]*big.Int{big.NewInt(4), big.NewInt(4)}
+)
+
+// compressG1 encodes a G1 point in 48 bytes.
+func compressG1(g *bls12381.G1, p *bls12381.PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] = compressedFlag | infinityFlag
+		return out
+	}
+	raw := g.ToBytes(new(bls12381.PointG1).Set(p))
+	copy(out, raw[:48])
+	out[0] |= compressedFlag
+	if new(big.Int).SetBytes(raw[48:]).Cmp(halfFieldModulus) > 0 {
+		out[0] |= signFlag
+	}
+	return out
+}
+
+// decompressG1 decodes a G1 point encoded in 48 bytes, which must be in the subgroup.
+func decompressG1(g *bls12381.G1, in []byte) (*bls12381.PointG1, error) {
+	x, flags, err := splitFlags(in, 48)
+	if err != nil {
+		return nil, err
+	}
+	if flags&infinityFlag != 0 {
+		return g.Zero(), nil
+	}
+	x0, err := fieldElement(x)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + 4
+	rhs := new(big.Int).Exp(x0, big.NewInt(3), fieldModulus)
+	rhs.Add(rhs, big.NewInt(4)).Mod(rhs, fieldModulus)
+	y := new(big.Int).ModSqrt(rhs, fieldModulus)
+	if y == nil {
+		return nil, errors.New("point is not on curve")
+	}
+	if (y.Cmp(halfFieldModulus) > 0) != (flags&signFlag != 0) {
+		y.Sub(fieldModulus, y)
+	}
+	raw := make([]byte, 96)
+	copy(raw, x)
+	y.FillBytes(raw[48:])
+	p, err := g.FromBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in the correct subgroup")
+	}
+	return p, nil
+}
+
+// compressG2 encodes a G2 point in 96 bytes.
+func compressG2(g *bls12381.G2, p *bls12381.PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] = compressedFlag | infinityFlag
+		return out
+	}
+	raw := g.ToBytes(new(bls12381.PointG2).Set(p))
+	copy(out, raw[:96])
+	out[0] |= compressedFlag
+	// Coordinates are encoded as c1 || c0, the sign is taken from c1 unless it is zero.
+	y1, y0 := new(big.Int).SetBytes(raw[96:144]), new(big.Int).SetBytes(raw[144:])
+	if (y1.Sign() != 0 && y1.Cmp(halfFieldModulus) > 0) || (y1.Sign() == 0 && y0.Cmp(halfFieldModulus) > 0) {
+		out[0] |= signFlag
+	}
+	return out
+}
+
+// decompressG2 decodes a G2 point encoded in 96 bytes, which must be in the subgroup.
+func decompressG2(g *bls12381.G2, in []byte) (*bls12381.PointG2, error) {
+	x, flags, err := splitFlags(in, 96)
+	if err != nil {
+		return nil, err
+	}
+	if flags&infinityFlag != 0 {
+		return g.Zero(), nil
+	}
+	x1, err := fieldElement(x[:48])
+	if err != nil {
+		return nil, err
+	}
+	x0, err := fieldElement(x[48:])
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + 4(1+i)
+	sq0, sq1 := fp2Mul(x0, x1, x0, x1)
+	rhs0, rhs1 := fp2Mul(sq0, sq1, x0, x1)
+	rhs0.Add(rhs0, twistB[0]).Mod(rhs0, fieldModulus)
+	rhs1.Add(rhs1, twistB[1]).Mod(rhs1, fieldModulus)
+	y0, y1, ok := fp2Sqrt(rhs0, rhs1)
+	if !ok {
+		return nil, errors.New("point is not on curve")
+	}
+	largest := y1.Cmp(halfFieldModulus) > 0
+	if y1.Sign() == 0 {
+		largest = y0.Cmp(halfFieldModulus) > 0
+	}
+	if largest != (flags&signFlag != 0) {
+		y0.Sub(fieldModulus, y0).Mod(y0, fieldModulus)
+		y1.Sub(fieldModulus, y1).Mod(y1, fieldModulus)
+	}
+	raw := make([]byte, 192)
+	copy(raw, x)
+	y1.FillBytes(raw[96:144])
+	y0.FillBytes(raw[144:])
+	p, err := g.FromBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in the correct subgroup")
+	}
+	return p, nil
+}
+
+// splitFlags checks the flags of a compressed point and returns its x coordinate
+// without them. The x coordinate of the point at infinity must be zero.
+func splitFlags(in []byte, size int) ([]byte, byte, error) {
+	if len(in) != size {
+		return nil, 0, errors.New("invalid compressed point length")
+	}
+	flags := in[0] & flagsMask
+	if flags&compressedFlag == 0 {
+		return nil, 0, errors.New("point is not compressed")
+	}
+	x := make([]byte, size)
+	copy(x, in)
+	x[0] &^= flagsMask
+	if flags&infinityFlag != 0 {
+		if flags&signFlag != 0 || new(big.Int).SetBytes(x).Sign() != 0 {
+			return nil, 0, errors.New("invalid encoding of the point at infinity")
+		}
+	}
+	return x, flags, nil
+}
+
+// fieldElement decodes a big endian element of the base field.
+func fieldElement(in []byte) (*big.Int, error) {
+	e := new(big.Int).SetBytes(in)
+	if e.Cmp(fieldModulus) >= 0 {
+		return nil, errors.New("coordinate is not smaller than the field modulus")
+	}
+	return e, nil
+}
+
+// fp2Mul multiplies a0 + a1*i and b0 + b1*i in the quadratic extension field, where
+// i^2 = -1.
+func fp2Mul(a0, a1, b0, b1 *big.Int) (*big.Int, *big.Int) {
+	c0 := new(big.Int).Mul(a0, b0)
+	c0.Sub(c0, new(big.Int).Mul(a1, b1)).Mod(c0, fieldModulus)
+	c1 := new(big.Int).Mul(a0, b1)
+	c1.Add(c1, new(big.Int).Mul(a1, b0)).Mod(c1, fieldModulus)
+	return c0, c1
+}
+
+// fp2Sqrt returns a square root of a0 + a1*i in the quadratic extension field, or
+// false if it is not a square.
+func fp2Sqrt(a0, a1 *big.Int) (*big.Int, *big.Int, bool) {
+	if a1.Sign() == 0 {
+		if x0 := new(big.Int).ModSqrt(a0, fieldModulus); x0 != nil {
+			return x0, new(big.Int), true
+		}
+		// (x1*i)^2 = -x1^2
+		neg := new(big.Int).Sub(fieldModulus, a0)
+		if x1 := new(big.Int).ModSqrt(neg, fieldModulus); x1 != nil {
+			return new(big.Int), x1, true
+		}
+		return nil, nil, false
+	}
+	// The norm a0^2 + a1^2 of a square is a square in the base field, and the root is
+	// x0 + x1*i with x0^2 = (a0 +- sqrt(norm)) / 2 and x1 = a1 / (2*x0).
+	norm := new(big.Int).Mul(a0, a0)
+	norm.Add(norm, new(big.Int).Mul(a1, a1)).Mod(norm, fieldModulus)
+	s := new(big.Int).ModSqrt(norm, fieldModulus)
+	if s == nil {
+		return nil, nil, false
+	}
+	halve := new(big.Int).Rsh(new(big.Int).Add(fieldModulus, big.NewInt(1)), 1)
+	var x0 *big.Int
+	for _, t := range []*big.Int{new(big.Int).Add(a0, s), new(big.Int).Sub(a0, s)} {
+		t.Mul(t, halve).Mod(t, fieldModulus)
+		if x0 = new(big.Int).ModSqrt(t, fieldModulus); x0 != nil && x0.Sign() != 0 {
+			break
+		}
+	}
+	if x0 == nil || x0.Sign() == 0 {
+		return nil, nil, false
+	}
+	x1 := new(big.Int).Lsh(x0, 1)
+	x1.ModInverse(x1, fieldModulus)
+	x1.Mul(x1, a1).Mod(x1, fieldModulus)
+	return x0, x1, true
+}
@@ -0,0 +1,72 @@
+package purego
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// dst is the domain separation tag of the proof of possession ciphersuite, which is
+// the one used by Ethereum 2.0.
+var dst = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// hashToG2 hashes a message to a G2 point, as hash_to_curve of the BLS12381G2_XMD:SHA-256_SSWU_RO_
+// suite. The mapping of go-ethereum clears the cofactor of each point, which commutes
+// with their addition.
+func hashToG2(g *bls12381.G2, msg []byte) *bls12381.PointG2 {
+	uniform := expandMessageXMD(msg, dst, 256)
+	q0, err := g.MapToCurve(fp2Bytes(uniform[:128]))
+	if err != nil {
+		// Field elements are reduced modulo p, so they are always valid.
+		panic(err)
+	}
+	q1, err := g.MapToCurve(fp2Bytes(uniform[128:]))
+	if err != nil {
+		panic(err)
+	}
+	return g.Affine(g.Add(g.New(), q0, q1))
+}
+
+// fp2Bytes reduces two 64 byte strings to the coordinates c0 and c1 of an element of
+// the quadratic extension field, and encodes it as c1 || c0 like go-ethereum.
+func fp2Bytes(in []byte) []byte {
+	out := make([]byte, 96)
+	c0 := new(big.Int).SetBytes(in[:64])
+	c1 := new(big.Int).SetBytes(in[64:])
+	c1.Mod(c1, fieldModulus).FillBytes(out[:48])
+	c0.Mod(c0, fieldModulus).FillBytes(out[48:])
+	return out
+}
+
+// expandMessageXMD expands a message to a uniformly random byte string of the given
+// length with SHA-256, as expand_message_xmd of the hash-to-curve specification.
+func expandMessageXMD(msg, dst []byte, length int) []byte {
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+	h := sha256.New()
+	h.Write(make([]byte, h.BlockSize()))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+	out := append(make([]byte, 0, length+sha256.Size), bi...)
+	for i := 2; len(out) < length; i++ {
+		xored := make([]byte, sha256.Size)
+		for j := range xored {
+			xored[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(xored)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length]
+}
@@ -0,0 +1,71 @@
+package purego
+
+import (
+	"fmt"
+
+	common "github.com/atif-konasl/eth-research/bls"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/pkg/errors"
+)
+
+// PublicKey used in the BLS signature scheme, a G1 point in affine coordinates.
+type PublicKey struct {
+	p *bls12381.PointG1
+}
+
+// PublicKeyFromBytes creates a BLS public key from a compressed BigEndian byte slice.
+func PublicKeyFromBytes(pubKey []byte) (common.PublicKey, error) {
+	if len(pubKey) != 48 {
+		return nil, fmt.Errorf("public key must be %d bytes", 48)
+	}
+	p, err := decompressG1(bls12381.NewG1(), pubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal bytes into public key")
+	}
+	pubKeyObj := &PublicKey{p: p}
+	if pubKeyObj.IsInfinite() {
+		return nil, common.ErrInfinitePubKey
+	}
+	return pubKeyObj, nil
+}
+
+// AggregatePublicKeys aggregates the provided raw public keys into a single key.
+func AggregatePublicKeys(pubs [][]byte) (common.PublicKey, error) {
+	if len(pubs) == 0 {
+		return &PublicKey{p: bls12381.NewG1().Zero()}, nil
+	}
+	p, err := PublicKeyFromBytes(pubs[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range pubs[1:] {
+		pubkey, err := PublicKeyFromBytes(k)
+		if err != nil {
+			return nil, err
+		}
+		p.Aggregate(pubkey)
+	}
+	return p, nil
+}
+
+// Marshal a public key into a compressed BigEndian byte slice.
+func (p *PublicKey) Marshal() []byte {
+	return compressG1(bls12381.NewG1(), p.p)
+}
+
+// Copy the public key to a new pointer reference.
+func (p *PublicKey) Copy() common.PublicKey {
+	return &PublicKey{p: new(bls12381.PointG1).Set(p.p)}
+}
+
+// IsInfinite checks if the public key is infinite.
+func (p *PublicKey) IsInfinite() bool {
+	return bls12381.NewG1().IsZero(p.p)
+}
+
+// Aggregate two public keys.
+func (p *PublicKey) Aggregate(p2 common.PublicKey) common.PublicKey {
+	g := bls12381.NewG1()
+	g.Affine(g.Add(p.p, p.p, p2.(*PublicKey).p))
+	return p
+}
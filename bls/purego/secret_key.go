@@ -0,0 +1,67 @@
+package purego
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	common "github.com/atif-konasl/eth-research/bls"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/pkg/errors"
+)
+
+// secretKey used in the BLS signature scheme, a scalar modulo the curve order.
+type secretKey struct {
+	k *big.Int
+}
+
+// RandKey creates a new private key using the CSPRNG of crypto/rand.
+func RandKey() (common.SecretKey, error) {
+	k, err := rand.Int(rand.Reader, curveOrder)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate secret key")
+	}
+	if k.Sign() == 0 {
+		return nil, errors.New("generated a zero secret key")
+	}
+	return &secretKey{k: k}, nil
+}
+
+// SecretKeyFromBytes creates a BLS private key from a BigEndian byte slice.
+func SecretKeyFromBytes(privKey []byte) (common.SecretKey, error) {
+	if len(privKey) != 32 {
+		return nil, fmt.Errorf("secret key must be %d bytes", 32)
+	}
+	k := new(big.Int).SetBytes(privKey)
+	if k.Cmp(curveOrder) >= 0 {
+		return nil, common.ErrSecretUnmarshal
+	}
+	if k.Sign() == 0 {
+		return nil, common.ErrZeroKey
+	}
+	return &secretKey{k: k}, nil
+}
+
+// PublicKey obtains the public key corresponding to the BLS secret key.
+func (s *secretKey) PublicKey() common.PublicKey {
+	g := bls12381.NewG1()
+	p := g.MulScalar(g.New(), g.One(), s.k)
+	return &PublicKey{p: g.Affine(p)}
+}
+
+// Sign a message using a secret key, hashing it to G2 first.
+func (s *secretKey) Sign(msg []byte) common.Signature {
+	g := bls12381.NewG2()
+	p := g.MulScalar(g.New(), hashToG2(g, msg), s.k)
+	return &Signature{s: g.Affine(p)}
+}
+
+// Marshal a secret key into a BigEndian byte slice.
+func (s *secretKey) Marshal() []byte {
+	return s.k.FillBytes(make([]byte, 32))
+}
+
+// IsZero checks if the secret key is a zero key.
+func (s *secretKey) IsZero() bool {
+	return s.k.Sign() == 0
+}
@@ -0,0 +1,132 @@
+package purego
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	common "github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/rand"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/pkg/errors"
+)
+
+// Signature used in the BLS signature scheme, a G2 point in affine coordinates.
+type Signature struct {
+	s *bls12381.PointG2
+}
+
+// SignatureFromBytes creates a BLS signature from a compressed BigEndian byte slice.
+func SignatureFromBytes(sig []byte) (common.Signature, error) {
+	if len(sig) != 96 {
+		return nil, fmt.Errorf("signature must be %d bytes", 96)
+	}
+	s, err := decompressG2(bls12381.NewG2(), sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal bytes into signature")
+	}
+	return &Signature{s: s}, nil
+}
+
+// Verify a bls signature given a public key, a message, by checking that
+// e(pubKey, H(msg)) == e(g1, signature).
+func (s *Signature) Verify(pubKey common.PublicKey, msg []byte) bool {
+	// Reject infinite public keys.
+	if pubKey.IsInfinite() {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(pubKey.(*PublicKey).p, hashToG2(engine.G2, msg))
+	engine.AddPairInv(engine.G1.One(), s.s)
+	return engine.Check()
+}
+
+// AggregateVerify verifies each public key against its respective message. Like in
+// the herumi package, the messages do not need to be distinct.
+func (s *Signature) AggregateVerify(pubKeys []common.PublicKey, msgs [][32]byte) bool {
+	size := len(pubKeys)
+	if size == 0 {
+		return false
+	}
+	if size != len(msgs) {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	for i := 0; i < size; i++ {
+		engine.AddPair(pubKeys[i].(*PublicKey).p, hashToG2(engine.G2, msgs[i][:]))
+	}
+	engine.AddPairInv(engine.G1.One(), s.s)
+	return engine.Check()
+}
+
+// FastAggregateVerify verifies all the provided public keys with their aggregated signature.
+func (s *Signature) FastAggregateVerify(pubKeys []common.PublicKey, msg [32]byte) bool {
+	if len(pubKeys) == 0 {
+		return false
+	}
+	aggregate := pubKeys[0].Copy()
+	for _, pubKey := range pubKeys[1:] {
+		aggregate.Aggregate(pubKey)
+	}
+	return s.Verify(aggregate, msg[:])
+}
+
+// AggregateSignatures converts a list of signatures into a single, aggregated sig.
+func AggregateSignatures(sigs []common.Signature) common.Signature {
+	if len(sigs) == 0 {
+		return nil
+	}
+	g := bls12381.NewG2()
+	signature := new(bls12381.PointG2).Set(sigs[0].(*Signature).s)
+	for i := 1; i < len(sigs); i++ {
+		g.Add(signature, signature, sigs[i].(*Signature).s)
+	}
+	return &Signature{s: g.Affine(signature)}
+}
+
+// VerifyMultipleSignatures verifies a non-singular set of signatures and its respective
+// pubkeys and messages, as in the herumi package. Every signature and public key is
+// multiplied by a random number, so a set of invalid signatures cannot cancel out:
+// S* = S_1 * r_1 + S_2 * r_2 + ... + S_n * r_n
+// e(S*, G) = \prod_{i=1}^n e(P_i * r_i, M_i)
+func VerifyMultipleSignatures(sigs []common.Signature, msgs [][32]byte, pubKeys []common.PublicKey) (bool, error) {
+	if len(sigs) == 0 || len(pubKeys) == 0 {
+		return false, nil
+	}
+	length := len(sigs)
+	if length != len(pubKeys) || length != len(msgs) {
+		return false, errors.Errorf("provided signatures, pubkeys and messages have differing lengths. S: %d, P: %d,M %d",
+			length, len(pubKeys), len(msgs))
+	}
+	// Use a secure source of RNG.
+	newGen := rand.NewGenerator()
+	engine := bls12381.NewPairingEngine()
+	finalSig := engine.G2.New()
+	for i := 0; i < length; i++ {
+		if pubKeys[i] == nil {
+			return false, errors.New("nil public key")
+		}
+		rNum := new(big.Int).SetUint64(newGen.Uint64())
+		sig := engine.G2.MulScalar(engine.G2.New(), sigs[i].(*Signature).s, rNum)
+		engine.G2.Add(finalSig, finalSig, sig)
+		pubKey := engine.G1.MulScalar(engine.G1.New(), pubKeys[i].(*PublicKey).p, rNum)
+		engine.AddPair(pubKey, hashToG2(engine.G2, msgs[i][:]))
+	}
+	engine.AddPairInv(engine.G1.One(), finalSig)
+	return engine.Check(), nil
+}
+
+// Marshal a signature into a compressed BigEndian byte slice.
+func (s *Signature) Marshal() []byte {
+	return compressG2(bls12381.NewG2(), s.s)
+}
+
+// Copy returns a full deep copy of a signature.
+func (s *Signature) Copy() common.Signature {
+	return &Signature{s: new(bls12381.PointG2).Set(s.s)}
+}
+
+// HexString returns the hex string of the marshaled signature.
+func (s *Signature) HexString() string {
+	return hex.EncodeToString(s.Marshal())
+}
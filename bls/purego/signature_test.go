@@ -0,0 +1,169 @@
+package purego
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	common "github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/testutil/assert"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+func TestSignVerify(t *testing.T) {
+	priv, err := RandKey()
+	require.NoError(t, err)
+	pub := priv.PublicKey()
+	msg := []byte("hello")
+	sig := priv.Sign(msg)
+	assert.DeepEqual(t, true, sig.Verify(pub, msg))
+	assert.DeepEqual(t, false, sig.Verify(pub, []byte("world")))
+}
+
+func TestAggregateVerify(t *testing.T) {
+	pubkeys := make([]common.PublicKey, 0, 20)
+	sigs := make([]common.Signature, 0, 20)
+	var msgs [][32]byte
+	for i := 0; i < 20; i++ {
+		msg := [32]byte{'h', 'e', 'l', 'l', 'o', byte(i)}
+		priv, err := RandKey()
+		require.NoError(t, err)
+		pub := priv.PublicKey()
+		sig := priv.Sign(msg[:])
+		pubkeys = append(pubkeys, pub)
+		sigs = append(sigs, sig)
+		msgs = append(msgs, msg)
+	}
+	aggSig := AggregateSignatures(sigs)
+	assert.DeepEqual(t, true, aggSig.AggregateVerify(pubkeys, msgs))
+	msgs[0][0] = 'j'
+	assert.DeepEqual(t, false, aggSig.AggregateVerify(pubkeys, msgs))
+}
+
+func TestFastAggregateVerify(t *testing.T) {
+	pubkeys := make([]common.PublicKey, 0, 20)
+	sigs := make([]common.Signature, 0, 20)
+	msg := [32]byte{'h', 'e', 'l', 'l', 'o'}
+	for i := 0; i < 20; i++ {
+		priv, err := RandKey()
+		require.NoError(t, err)
+		pub := priv.PublicKey()
+		sig := priv.Sign(msg[:])
+		pubkeys = append(pubkeys, pub)
+		sigs = append(sigs, sig)
+	}
+	aggSig := AggregateSignatures(sigs)
+	assert.DeepEqual(t, true, aggSig.FastAggregateVerify(pubkeys, msg))
+	assert.DeepEqual(t, false, aggSig.FastAggregateVerify(pubkeys[1:], msg))
+}
+
+func TestMultipleSignatureVerification_FailsCorrectly(t *testing.T) {
+	pubkeys := make([]common.PublicKey, 0, 20)
+	sigs := make([]common.Signature, 0, 20)
+	var msgs [][32]byte
+	for i := 0; i < 20; i++ {
+		msg := [32]byte{'h', 'e', 'l', 'l', 'o', byte(i)}
+		priv, err := RandKey()
+		require.NoError(t, err)
+		pubkeys = append(pubkeys, priv.PublicKey())
+		sigs = append(sigs, priv.Sign(msg[:]))
+		msgs = append(msgs, msg)
+	}
+	verify, err := VerifyMultipleSignatures(sigs, msgs, pubkeys)
+	assert.NoError(t, err)
+	assert.Equal(t, true, verify, "Signature did not verify")
+
+	// Adding a point to a signature and subtracting it from another leaves their
+	// aggregate valid, which only the randomized verification detects.
+	g := bls12381.NewG2()
+	offset := hashToG2(g, []byte("offset"))
+	first := sigs[len(sigs)-1].Copy().(*Signature)
+	second := sigs[len(sigs)-2].Copy().(*Signature)
+	g.Affine(g.Add(first.s, first.s, offset))
+	g.Affine(g.Sub(second.s, second.s, offset))
+	sigs[len(sigs)-1], sigs[len(sigs)-2] = first, second
+
+	aggSig := AggregateSignatures(sigs)
+	assert.Equal(t, true, aggSig.AggregateVerify(pubkeys, msgs), "Signature did not verify")
+	verify, err = VerifyMultipleSignatures(sigs, msgs, pubkeys)
+	assert.NoError(t, err)
+	assert.Equal(t, false, verify, "Signature verified when it was not supposed to")
+}
+
+func TestFastAggregateVerify_ReturnsFalseOnEmptyPubKeyList(t *testing.T) {
+	var pubkeys []common.PublicKey
+	msg := [32]byte{'h', 'e', 'l', 'l', 'o'}
+
+	aggSig := &Signature{s: hashToG2(bls12381.NewG2(), []byte("mock"))}
+	assert.Equal(t, false, aggSig.FastAggregateVerify(pubkeys, msg))
+}
+
+func TestSignatureFromBytes(t *testing.T) {
+	good, err := hex.DecodeString("abb0124c7574f281a293f4185cad3cb22681d520917ce46665243eacb051000d8bacf75e1451870ca6b3b9e6c9d41a7b02ead2685a84188a4fafd3825daf6a989625d719ccd2d83a40101f4a453fca62878c890eca622363f9ddb8f367a91e84")
+	require.NoError(t, err)
+	tests := []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{
+			name: "Nil",
+			err:  errors.New("signature must be 96 bytes"),
+		},
+		{
+			name:  "Short",
+			input: make([]byte, 95),
+			err:   errors.New("signature must be 96 bytes"),
+		},
+		{
+			name:  "Long",
+			input: make([]byte, 97),
+			err:   errors.New("signature must be 96 bytes"),
+		},
+		{
+			name:  "Bad",
+			input: make([]byte, 96),
+			err:   errors.New("could not unmarshal bytes into signature: point is not compressed"),
+		},
+		{
+			name:  "Good",
+			input: good,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := SignatureFromBytes(test.input)
+			if test.err != nil {
+				assert.ErrorContains(t, test.err.Error(), err)
+			} else {
+				assert.NoError(t, err)
+				assert.DeepEqual(t, test.input, res.Marshal())
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	g := bls12381.NewG2()
+	signatureA := &Signature{s: hashToG2(g, []byte("foo"))}
+	signatureB, ok := signatureA.Copy().(*Signature)
+	require.Equal(t, true, ok)
+
+	assert.NotEqual(t, signatureA, signatureB)
+	assert.NotEqual(t, signatureA.s, signatureB.s)
+	assert.DeepEqual(t, signatureA, signatureB)
+
+	g.Add(signatureA.s, signatureA.s, hashToG2(g, []byte("bar")))
+	assert.DeepNotEqual(t, signatureA, signatureB)
+}
+
+func TestExpandMessageXMD(t *testing.T) {
+	// Test vector of expand_message_xmd with SHA-256 from the hash-to-curve specification.
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	assert.Equal(t,
+		"68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235",
+		hex.EncodeToString(expandMessageXMD([]byte{}, dst, 32)),
+	)
+}
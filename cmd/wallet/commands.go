@@ -0,0 +1,563 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/wallet"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+func createWallet(cliCtx *cli.Context) error {
+	ctx := context.Background()
+	kind, err := wallet.ParseKind(cliCtx.String(KeymanagerKindFlag.Name))
+	if err != nil {
+		return err
+	}
+	kdfParams, err := kdfParamsFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	cfg := &wallet.Config{
+		WalletDir:      cliCtx.GlobalString(WalletDirFlag.Name),
+		KeymanagerKind: kind,
+		PasswordSource: walletPasswordSource(cliCtx, true),
+		KDFParams:      kdfParams,
+	}
+	if kind == wallet.Remote {
+		cfg.RemoteKeymanagerOpts = &wallet.RemoteKeymanagerOpts{
+			RemoteAddr:    cliCtx.String(RemoteAddressFlag.Name),
+			AllowInsecure: cliCtx.Bool(RemoteAllowInsecureFlag.Name),
+		}
+		if caCert := cliCtx.String(RemoteCACertFlag.Name); caCert != "" {
+			cfg.RemoteKeymanagerOpts.RemoteCertificate = &wallet.RemoteCertificateOpts{
+				RequireTls:     true,
+				CACertPath:     caCert,
+				ClientCertPath: cliCtx.String(RemoteClientCertFlag.Name),
+				ClientKeyPath:  cliCtx.String(RemoteClientKeyFlag.Name),
+			}
+		}
+	}
+	// The mnemonic is read before the wallet is created, so a wallet is never left
+	// behind without its seed.
+	var mnemonic string
+	var generated bool
+	if kind == wallet.Derived {
+		if mnemonic, generated, err = mnemonicFromFlags(cliCtx); err != nil {
+			return err
+		}
+	}
+	w, err := wallet.CreateWallet(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "could not create wallet")
+	}
+	defer closeWallet(w)
+
+	result := &createResult{WalletDir: cfg.WalletDir, KeymanagerKind: kind.String()}
+	if kind == wallet.Derived {
+		numAccounts := cliCtx.Uint64(NumAccountsFlag.Name)
+		km, err := wallet.RecoverFromMnemonic(ctx, w, mnemonic, "", numAccounts, false /* overwrite */)
+		if err != nil {
+			return err
+		}
+		pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+		result.PublicKeys = hexPublicKeys(pubKeys)
+		if generated {
+			result.Mnemonic = mnemonic
+		}
+	}
+	return printResult(cliCtx, result)
+}
+
+func listAccounts(cliCtx *cli.Context) error {
+	ctx := context.Background()
+	w, km, err := openKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	result := &listResult{}
+	if importedKm, ok := km.(*wallet.Keymanager); ok {
+		accounts, err := importedKm.ListAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			result.Accounts = append(result.Accounts, newAccountResult(account))
+		}
+		return printResult(cliCtx, result)
+	}
+	pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for i, pubKey := range pubKeys {
+		result.Accounts = append(result.Accounts, &accountResult{Index: i, PublicKey: fmt.Sprintf("%#x", pubKey)})
+	}
+	return printResult(cliCtx, result)
+}
+
+func importAccounts(cliCtx *cli.Context) error {
+	keysDir := cliCtx.String(KeysDirFlag.Name)
+	if keysDir == "" {
+		return errors.New("--keys-dir is required")
+	}
+	keystores, err := wallet.ReadKeystoresFromDir(keysDir)
+	if err != nil {
+		return err
+	}
+	if len(keystores) == 0 {
+		return fmt.Errorf("no keystores found in %s", keysDir)
+	}
+	w, km, err := openImportedKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	password, err := passwordFromFlags(cliCtx, KeystoresPasswordFileFlag.Name, "Keystores password", false)
+	if err != nil {
+		return err
+	}
+	passwords := make([]string, len(keystores))
+	pubKeys := make([]string, len(keystores))
+	for i, keystore := range keystores {
+		passwords[i] = password
+		pubKeys[i] = "0x" + strings.TrimPrefix(keystore.Pubkey, "0x")
+	}
+	if err := km.ImportKeystores(context.Background(), keystores, passwords); err != nil {
+		return err
+	}
+	return printResult(cliCtx, &accountsResult{Action: "imported", PublicKeys: pubKeys})
+}
+
+func exportAccounts(cliCtx *cli.Context) error {
+	pubKeys, err := publicKeysFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	outputDir := cliCtx.String(OutputDirFlag.Name)
+	if outputDir == "" {
+		return errors.New("--output-dir is required")
+	}
+	w, km, err := openImportedKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	password, err := passwordFromFlags(cliCtx, ExportPasswordFileFlag.Name, "Export password", true)
+	if err != nil {
+		return err
+	}
+	keystores, err := km.ExportKeystores(pubKeys, password, cliCtx.Bool(IncludeDisabledFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err := wallet.WriteKeystoresToDir(outputDir, keystores); err != nil {
+		return err
+	}
+	return printResult(cliCtx, &accountsResult{Action: "exported", PublicKeys: hexPublicKeys(pubKeys), OutputDir: outputDir})
+}
+
+func deleteAccounts(cliCtx *cli.Context) error {
+	// Deleted keys cannot be recovered, so they are only dropped without an archive
+	// when asked for explicitly.
+	outputDir := cliCtx.String(OutputDirFlag.Name)
+	noArchive := cliCtx.Bool(NoArchiveFlag.Name)
+	if outputDir == "" && !noArchive {
+		return fmt.Errorf("--%s or --%s is required", OutputDirFlag.Name, NoArchiveFlag.Name)
+	}
+	if outputDir != "" && noArchive {
+		return fmt.Errorf("--%s cannot be combined with --%s", OutputDirFlag.Name, NoArchiveFlag.Name)
+	}
+	pubKeys, err := publicKeysFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	// The password is read once, to open the wallet and to encrypt the archive.
+	password, err := walletPasswordSource(cliCtx, false).WalletPassword()
+	if err != nil {
+		return errors.Wrap(err, "could not read wallet password")
+	}
+	w, km, err := openImportedKeymanager(cliCtx, password)
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	// The accounts are archived before they are deleted, so a failure to archive
+	// them leaves the wallet untouched.
+	if !noArchive {
+		archived, err := km.ExportKeystores(pubKeys, password, true /* includeDisabled */)
+		if err != nil {
+			return err
+		}
+		if err := wallet.WriteKeystoresToDir(outputDir, archived); err != nil {
+			return errors.Wrap(err, "could not archive accounts, none were deleted")
+		}
+	}
+	if _, err := km.DeleteAccounts(context.Background(), pubKeys); err != nil {
+		return err
+	}
+	return printResult(cliCtx, &accountsResult{Action: "deleted", PublicKeys: hexPublicKeys(pubKeys), OutputDir: outputDir})
+}
+
+func disableAccounts(cliCtx *cli.Context) error {
+	return setAccountsDisabled(cliCtx, true)
+}
+
+func enableAccounts(cliCtx *cli.Context) error {
+	return setAccountsDisabled(cliCtx, false)
+}
+
+func setAccountsDisabled(cliCtx *cli.Context, disabled bool) error {
+	pubKeys, err := publicKeysFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	w, km, err := openImportedKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	if disabled {
+		err = km.DisableAccounts(context.Background(), pubKeys)
+	} else {
+		err = km.EnableAccounts(context.Background(), pubKeys)
+	}
+	if err != nil {
+		return err
+	}
+	action := "enabled"
+	if disabled {
+		action = "disabled"
+	}
+	return printResult(cliCtx, &accountsResult{Action: action, PublicKeys: hexPublicKeys(pubKeys)})
+}
+
+func changePassword(cliCtx *cli.Context) error {
+	walletDir := cliCtx.GlobalString(WalletDirFlag.Name)
+	oldPassword, err := walletPasswordSource(cliCtx, false).WalletPassword()
+	if err != nil {
+		return errors.Wrap(err, "could not read wallet password")
+	}
+	kdfParams, err := kdfParamsFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	w, err := wallet.OpenWallet(context.Background(), &wallet.Config{
+		WalletDir:      walletDir,
+		WalletPassword: oldPassword,
+		KDFParams:      kdfParams,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not open wallet")
+	}
+	defer closeWallet(w)
+	newPassword, err := passwordFromFlags(cliCtx, NewWalletPasswordFileFlag.Name, wallet.NewWalletPasswordPromptText, true)
+	if err != nil {
+		return err
+	}
+	if err := w.ChangePassword(context.Background(), oldPassword, newPassword); err != nil {
+		return err
+	}
+	return printResult(cliCtx, &changePasswordResult{WalletDir: walletDir, Changed: true})
+}
+
+func sign(cliCtx *cli.Context) error {
+	slotInfo, domain, pubKey, err := signingRequestFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	// The keymanager checks the slashing protection database of the wallet unless
+	// another directory is given.
+	dir := cliCtx.String(SlashingProtectionDirFlag.Name)
+	if dir != "" && cliCtx.Bool(NoSlashingProtectionFlag.Name) {
+		return fmt.Errorf("--%s cannot be combined with --%s", SlashingProtectionDirFlag.Name, NoSlashingProtectionFlag.Name)
+	}
+	walletDir := cliCtx.GlobalString(WalletDirFlag.Name)
+	if dir != "" && filepath.Clean(dir) == filepath.Join(walletDir, wallet.SlashingProtectionDirName) {
+		dir = ""
+	}
+	w, km, err := openKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	if dir != "" {
+		store, err := slashingprotection.NewStore(dir)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := store.Close(); err != nil {
+				log.WithError(err).Error("Could not close slashing protection database")
+			}
+		}()
+		protected, ok := km.(interface {
+			UseSlashingProtection(store *slashingprotection.Store)
+		})
+		if !ok {
+			return fmt.Errorf("%s wallets do not support slashing protection", w.KeymanagerKind())
+		}
+		protected.UseSlashingProtection(store)
+	}
+	if cliCtx.Bool(AuditLogFlag.Name) {
+		audited, ok := km.(*wallet.Keymanager)
+		if !ok {
+			return fmt.Errorf("%s wallets do not support audit logs", w.KeymanagerKind())
+		}
+		auditLog, err := wallet.NewAuditLog(walletDir)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := auditLog.Close(); err != nil {
+				log.WithError(err).Error("Could not close audit log")
+			}
+		}()
+		audited.UseAuditLog(auditLog)
+	}
+	if err := setEncoding(cliCtx, km); err != nil {
+		return err
+	}
+	signature, err := km.Sign(slotInfo, domain, pubKey)
+	if err != nil {
+		return err
+	}
+	return printResult(cliCtx, &signatureResult{
+		PublicKey: fmt.Sprintf("%#x", pubKey),
+		Signature: fmt.Sprintf("%#x", signature.Marshal()),
+	})
+}
+
+func verify(cliCtx *cli.Context) error {
+	slotInfo, domain, pubKey, err := signingRequestFromFlags(cliCtx)
+	if err != nil {
+		return err
+	}
+	signatureBytes, err := decodeHex(cliCtx.String(SignatureFlag.Name))
+	if err != nil {
+		return errors.Wrap(err, "could not decode --signature")
+	}
+	signature, err := bls.SignatureFromBytes(signatureBytes)
+	if err != nil {
+		return err
+	}
+	w, km, err := openKeymanager(cliCtx, "")
+	if err != nil {
+		return err
+	}
+	defer closeWallet(w)
+	if err := setEncoding(cliCtx, km); err != nil {
+		return err
+	}
+	if err := km.VerifySignature(slotInfo, domain, pubKey, signature); err != nil {
+		return err
+	}
+	return printResult(cliCtx, &verifyResult{PublicKey: fmt.Sprintf("%#x", pubKey), Valid: true})
+}
+
+// mnemonicFromFlags reads the mnemonic of a derived wallet from its file, or generates
+// a new one. Returns whether the mnemonic was generated.
+func mnemonicFromFlags(cliCtx *cli.Context) (string, bool, error) {
+	path := cliCtx.String(MnemonicFileFlag.Name)
+	if path == "" {
+		mnemonic, err := wallet.GenerateMnemonic()
+		return mnemonic, true, err
+	}
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, errors.Wrap(err, "could not read mnemonic file")
+	}
+	mnemonic := strings.TrimSpace(string(encoded))
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return "", false, errors.New("mnemonic file does not hold a valid mnemonic")
+	}
+	return mnemonic, false, nil
+}
+
+// publicKeysFromFlags decodes the public keys of the --public-keys flag.
+func publicKeysFromFlags(cliCtx *cli.Context) ([][48]byte, error) {
+	value := cliCtx.String(PublicKeysFlag.Name)
+	if value == "" {
+		return nil, errors.New("--public-keys is required")
+	}
+	var pubKeys [][48]byte
+	for _, hexKey := range strings.Split(value, ",") {
+		pubKey, err := decodePublicKey(strings.TrimSpace(hexKey))
+		if err != nil {
+			return nil, err
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	return pubKeys, nil
+}
+
+func decodePublicKey(hexKey string) ([48]byte, error) {
+	pubKey, err := decodeHex(hexKey)
+	if err != nil {
+		return [48]byte{}, errors.Wrapf(err, "could not decode public key %s", hexKey)
+	}
+	if len(pubKey) != 48 {
+		return [48]byte{}, fmt.Errorf("public key %s must be %d bytes", hexKey, 48)
+	}
+	return bytesutil.ToBytes48(pubKey), nil
+}
+
+// openWallet opens the wallet of the --wallet-dir flag with the given password, or the
+// one read from the password sources of the flags if it is empty. The caller must close it.
+func openWallet(cliCtx *cli.Context, password string) (*wallet.Wallet, error) {
+	kdfParams, err := kdfParamsFromFlags(cliCtx)
+	if err != nil {
+		return nil, err
+	}
+	w, err := wallet.OpenWallet(context.Background(), &wallet.Config{
+		WalletDir:      cliCtx.GlobalString(WalletDirFlag.Name),
+		WalletPassword: password,
+		PasswordSource: walletPasswordSource(cliCtx, false),
+		KDFParams:      kdfParams,
+
+		DisableSlashingProtection: cliCtx.Bool(NoSlashingProtectionFlag.Name),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open wallet")
+	}
+	return w, nil
+}
+
+// openKeymanager opens the wallet and its keymanager, whatever its kind.
+func openKeymanager(cliCtx *cli.Context, password string) (*wallet.Wallet, wallet.IKeymanager, error) {
+	w, err := openWallet(cliCtx, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	km, err := wallet.NewKeymanager(context.Background(), w)
+	if err != nil {
+		closeWallet(w)
+		return nil, nil, errors.Wrap(err, "could not initialize keymanager")
+	}
+	return w, km, nil
+}
+
+// openImportedKeymanager opens the wallet and its keymanager, which must manage
+// imported accounts.
+func openImportedKeymanager(cliCtx *cli.Context, password string) (*wallet.Wallet, *wallet.Keymanager, error) {
+	w, km, err := openKeymanager(cliCtx, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	importedKm, ok := km.(*wallet.Keymanager)
+	if !ok {
+		closeWallet(w)
+		return nil, nil, fmt.Errorf("%s wallets do not manage imported accounts", w.KeymanagerKind())
+	}
+	return w, importedKm, nil
+}
+
+func closeWallet(w *wallet.Wallet) {
+	if err := w.Close(); err != nil {
+		log.WithError(err).Error("Could not close wallet")
+	}
+}
+
+// walletPasswordSource reads the wallet password from the --wallet-password-file flag
+// if set, or from the default sources of the wallet otherwise. The password of a new
+// wallet is confirmed when prompted for.
+func walletPasswordSource(cliCtx *cli.Context, newWallet bool) wallet.PasswordSource {
+	if path := cliCtx.GlobalString(WalletPasswordFileFlag.Name); path != "" {
+		return wallet.PasswordFile(path)
+	}
+	if newWallet {
+		return wallet.PasswordSources{
+			wallet.EnvPassword(wallet.WalletPasswordEnvVar),
+			wallet.NewPasswordPrompt(wallet.NewWalletPasswordPromptText, true),
+		}
+	}
+	return wallet.DefaultPasswordSource(cliCtx.GlobalString(WalletDirFlag.Name))
+}
+
+// passwordFromFlags reads a password from the file of the given flag, prompting for it
+// if the flag is not set.
+func passwordFromFlags(cliCtx *cli.Context, flagName, promptText string, confirm bool) (string, error) {
+	if path := cliCtx.String(flagName); path != "" {
+		return wallet.PasswordFile(path).WalletPassword()
+	}
+	return wallet.NewPasswordPrompt(promptText, confirm).WalletPassword()
+}
+
+// kdfParamsFromFlags returns nil unless --keystore-kdf is set, so wallets keep writing
+// keystores with the KDF they were created with.
+func kdfParamsFromFlags(cliCtx *cli.Context) (*wallet.KDFParams, error) {
+	if !cliCtx.GlobalIsSet(KDFFlag.Name) {
+		return nil, nil
+	}
+	switch kdf := cliCtx.GlobalString(KDFFlag.Name); kdf {
+	case "pbkdf2":
+		return wallet.DefaultKDFParams(), nil
+	case "scrypt":
+		return wallet.ScryptKDFParams(), nil
+	case "fast":
+		return wallet.FastKDFParams(), nil
+	default:
+		return nil, fmt.Errorf("%s is not an allowed keystore KDF", kdf)
+	}
+}
+
+// signingRequestFromFlags decodes the slot info, domain and public key of a signature.
+func signingRequestFromFlags(cliCtx *cli.Context) (*wallet.SlotInfo, wallet.Domain, [48]byte, error) {
+	pubKey, err := decodePublicKey(cliCtx.String(PublicKeyFlag.Name))
+	if err != nil {
+		return nil, wallet.Domain{}, [48]byte{}, err
+	}
+	forkVersion, err := decodeHex(cliCtx.String(ForkVersionFlag.Name))
+	if err != nil || len(forkVersion) != 4 {
+		return nil, wallet.Domain{}, [48]byte{}, errors.New("--fork-version must be 4 hex bytes")
+	}
+	genesisValidatorsRoot, err := decodeHex(cliCtx.String(GenesisValidatorsRootFlag.Name))
+	if err != nil || len(genesisValidatorsRoot) != 32 {
+		return nil, wallet.Domain{}, [48]byte{}, errors.New("--genesis-validators-root must be 32 hex bytes")
+	}
+	domain := wallet.ComputeDomain(
+		wallet.DomainSlotInfo, bytesutil.ToBytes4(forkVersion), bytesutil.ToBytes32(genesisValidatorsRoot),
+	)
+	slotInfo := wallet.NewSlotInfo(
+		cliCtx.Uint64(EpochFlag.Name), cliCtx.Uint64(SlotFlag.Name), cliCtx.Uint64(ProposerIndexFlag.Name),
+	)
+	return slotInfo, domain, pubKey, nil
+}
+
+// setEncoding sets the slot info encoding of the --encoding flag on the keymanager.
+func setEncoding(cliCtx *cli.Context, km wallet.IKeymanager) error {
+	var encoding wallet.Encoding
+	switch value := cliCtx.String(EncodingFlag.Name); value {
+	case "rlp":
+		encoding = wallet.RLPEncoding
+	case "ssz":
+		encoding = wallet.SSZEncoding
+	default:
+		return fmt.Errorf("%s is not an allowed slot info encoding", value)
+	}
+	encoder, ok := km.(interface {
+		SetEncoding(encoding wallet.Encoding)
+	})
+	if !ok {
+		return errors.New("keymanager does not support slot info encodings")
+	}
+	encoder.SetEncoding(encoding)
+	return nil
+}
+
+func decodeHex(value string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(value, "0x"))
+}
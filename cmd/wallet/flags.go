@@ -0,0 +1,148 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	WalletDirFlag = cli.StringFlag{
+		Name:  "wallet-dir",
+		Value: filepath.Join(fileutil.HomeDir(), ".eth-research", "wallet"),
+		Usage: "Directory of the wallet",
+	}
+	WalletPasswordFileFlag = cli.StringFlag{
+		Name:  "wallet-password-file",
+		Usage: "File holding the wallet password, read instead of the WALLET_PASSWORD environment variable or a prompt",
+	}
+	KDFFlag = cli.StringFlag{
+		Name:  "keystore-kdf",
+		Usage: "KDF of the keystores written by the wallet: pbkdf2 (default), scrypt or fast (tests only). Wallets keep the KDF they were created with unless set",
+	}
+	JSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print results as JSON",
+	}
+	BLSBackendFlag = cli.StringFlag{
+		Name:  "bls-backend",
+		Usage: "BLS backend signing and verifying: herumi (default, needs cgo) or purego (not side-channel safe)",
+	}
+
+	KeymanagerKindFlag = cli.StringFlag{
+		Name:  "keymanager-kind",
+		Value: "direct",
+		Usage: "Kind of the new wallet: direct (imported keystores), derived or remote",
+	}
+	MnemonicFileFlag = cli.StringFlag{
+		Name:  "mnemonic-file",
+		Usage: "File holding the mnemonic to recover a derived wallet from, a new one is generated otherwise",
+	}
+	NumAccountsFlag = cli.Uint64Flag{
+		Name:  "num-accounts",
+		Value: 1,
+		Usage: "Number of accounts to derive in a new derived wallet",
+	}
+	RemoteAddressFlag = cli.StringFlag{
+		Name:  "remote-address",
+		Usage: "Address of the remote signer of a remote wallet",
+	}
+	RemoteCACertFlag = cli.StringFlag{
+		Name:  "remote-ca-crt",
+		Usage: "CA certificate of the remote signer, enabling mutual TLS",
+	}
+	RemoteClientCertFlag = cli.StringFlag{
+		Name:  "remote-client-crt",
+		Usage: "Client certificate for mutual TLS with the remote signer",
+	}
+	RemoteClientKeyFlag = cli.StringFlag{
+		Name:  "remote-client-key",
+		Usage: "Client key for mutual TLS with the remote signer",
+	}
+	RemoteAllowInsecureFlag = cli.BoolFlag{
+		Name:  "remote-allow-insecure",
+		Usage: "Allow connecting to the remote signer without TLS, sending signing requests in cleartext",
+	}
+
+	PublicKeysFlag = cli.StringFlag{
+		Name:  "public-keys",
+		Usage: "Comma separated hex public keys of the accounts",
+	}
+	KeysDirFlag = cli.StringFlag{
+		Name:  "keys-dir",
+		Usage: "Directory of the EIP-2335 keystores to import",
+	}
+	KeystoresPasswordFileFlag = cli.StringFlag{
+		Name:  "keystores-password-file",
+		Usage: "File holding the password of the keystores to import",
+	}
+	OutputDirFlag = cli.StringFlag{
+		Name:  "output-dir",
+		Usage: "Directory to write keystores to",
+	}
+	NoArchiveFlag = cli.BoolFlag{
+		Name:  "no-archive",
+		Usage: "Delete accounts without archiving them to --output-dir, destroying their keys",
+	}
+	ExportPasswordFileFlag = cli.StringFlag{
+		Name:  "export-password-file",
+		Usage: "File holding the password to encrypt exported keystores with",
+	}
+	IncludeDisabledFlag = cli.BoolFlag{
+		Name:  "include-disabled",
+		Usage: "Export disabled accounts as well",
+	}
+	NewWalletPasswordFileFlag = cli.StringFlag{
+		Name:  "new-wallet-password-file",
+		Usage: "File holding the new wallet password",
+	}
+
+	PublicKeyFlag = cli.StringFlag{
+		Name:  "public-key",
+		Usage: "Hex public key of the account",
+	}
+	EpochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Epoch of the slot info",
+	}
+	SlotFlag = cli.Uint64Flag{
+		Name:  "slot",
+		Usage: "Slot of the slot info",
+	}
+	ProposerIndexFlag = cli.Uint64Flag{
+		Name:  "proposer-index",
+		Usage: "Proposer index of the slot info",
+	}
+	ForkVersionFlag = cli.StringFlag{
+		Name:  "fork-version",
+		Value: "0x00000000",
+		Usage: "Hex fork version of the signing domain",
+	}
+	GenesisValidatorsRootFlag = cli.StringFlag{
+		Name:  "genesis-validators-root",
+		Value: "0x0000000000000000000000000000000000000000000000000000000000000000",
+		Usage: "Hex genesis validators root of the signing domain",
+	}
+	EncodingFlag = cli.StringFlag{
+		Name:  "encoding",
+		Value: "rlp",
+		Usage: "Encoding of the slot info the signing root is computed from: rlp or ssz",
+	}
+	SignatureFlag = cli.StringFlag{
+		Name:  "signature",
+		Usage: "Hex signature to verify",
+	}
+	SlashingProtectionDirFlag = cli.StringFlag{
+		Name:  "slashing-protection-dir",
+		Usage: "Directory of the slashing protection database checked before signing, slashing-protection in the wallet directory by default",
+	}
+	NoSlashingProtectionFlag = cli.BoolFlag{
+		Name:  "no-slashing-protection",
+		Usage: "Sign without checking the slashing protection database, risking a double proposal",
+	}
+	AuditLogFlag = cli.BoolFlag{
+		Name:  "audit-log",
+		Usage: "Record the signing request in the audit log of the wallet directory",
+	}
+)
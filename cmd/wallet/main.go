@@ -0,0 +1,173 @@
+// Command wallet manages the accounts of a wallet and signs slot infos with them.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/atif-konasl/eth-research/bls"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	app = cli.NewApp()
+
+	signingFlags = []cli.Flag{
+		PublicKeyFlag,
+		EpochFlag,
+		SlotFlag,
+		ProposerIndexFlag,
+		ForkVersionFlag,
+		GenesisValidatorsRootFlag,
+		EncodingFlag,
+	}
+
+	createCommand = cli.Command{
+		Action:    createWallet,
+		Name:      "create",
+		Usage:     "Create a new wallet",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			KeymanagerKindFlag,
+			MnemonicFileFlag,
+			NumAccountsFlag,
+			RemoteAddressFlag,
+			RemoteCACertFlag,
+			RemoteClientCertFlag,
+			RemoteClientKeyFlag,
+			RemoteAllowInsecureFlag,
+		},
+		Description: `
+The create command lays out a new wallet in the wallet directory. Derived wallets
+are recovered from the given mnemonic, or from a new one which is printed once.`,
+	}
+	listCommand = cli.Command{
+		Action:    listAccounts,
+		Name:      "list",
+		Usage:     "List the accounts of the wallet",
+		ArgsUsage: "",
+	}
+	importCommand = cli.Command{
+		Action:    importAccounts,
+		Name:      "import",
+		Usage:     "Import EIP-2335 keystores into the wallet",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			KeysDirFlag,
+			KeystoresPasswordFileFlag,
+		},
+	}
+	exportCommand = cli.Command{
+		Action:    exportAccounts,
+		Name:      "export",
+		Usage:     "Export accounts of the wallet as EIP-2335 keystores",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			PublicKeysFlag,
+			OutputDirFlag,
+			ExportPasswordFileFlag,
+			IncludeDisabledFlag,
+		},
+	}
+	deleteCommand = cli.Command{
+		Action:    deleteAccounts,
+		Name:      "delete",
+		Usage:     "Delete accounts from the wallet",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			PublicKeysFlag,
+			OutputDirFlag,
+			NoArchiveFlag,
+		},
+		Description: `
+The delete command removes accounts from the wallet. The removed accounts are first
+written to --output-dir as keystores encrypted with the wallet password. Deleting them
+without an archive, which cannot be undone, requires --no-archive instead.`,
+	}
+	disableCommand = cli.Command{
+		Action:    disableAccounts,
+		Name:      "disable",
+		Usage:     "Disable accounts of the wallet, refusing to sign with them",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			PublicKeysFlag,
+		},
+	}
+	enableCommand = cli.Command{
+		Action:    enableAccounts,
+		Name:      "enable",
+		Usage:     "Enable disabled accounts of the wallet",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			PublicKeysFlag,
+		},
+	}
+	changePasswordCommand = cli.Command{
+		Action:    changePassword,
+		Name:      "change-password",
+		Usage:     "Change the wallet password",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			NewWalletPasswordFileFlag,
+		},
+	}
+	signCommand = cli.Command{
+		Action:    sign,
+		Name:      "sign",
+		Usage:     "Sign a slot info with an account of the wallet",
+		ArgsUsage: "",
+		Flags: append([]cli.Flag{
+			SlashingProtectionDirFlag,
+			NoSlashingProtectionFlag,
+			AuditLogFlag,
+		}, signingFlags...),
+	}
+	verifyCommand = cli.Command{
+		Action:    verify,
+		Name:      "verify",
+		Usage:     "Verify a signature of a slot info by an account of the wallet",
+		ArgsUsage: "",
+		Flags:     append([]cli.Flag{SignatureFlag}, signingFlags...),
+	}
+)
+
+func init() {
+	app.Name = "wallet"
+	app.Usage = "Manage the accounts of a wallet and sign with them"
+	app.Flags = []cli.Flag{
+		WalletDirFlag,
+		WalletPasswordFileFlag,
+		KDFFlag,
+		JSONFlag,
+		BLSBackendFlag,
+	}
+	app.Before = func(cliCtx *cli.Context) error {
+		if backend := cliCtx.GlobalString(BLSBackendFlag.Name); backend != "" {
+			return bls.UseBackend(backend)
+		}
+		if bls.BackendName() == "" {
+			return fmt.Errorf("the default BLS backend %s is not available, select one of %v with --%s",
+				bls.DefaultBackend, bls.Backends(), BLSBackendFlag.Name)
+		}
+		return nil
+	}
+	app.Commands = []cli.Command{
+		createCommand,
+		listCommand,
+		importCommand,
+		exportCommand,
+		deleteCommand,
+		disableCommand,
+		enableCommand,
+		changePasswordCommand,
+		signCommand,
+		verifyCommand,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
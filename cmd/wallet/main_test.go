@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bls/purego"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/atif-konasl/eth-research/wallet"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+	"github.com/google/uuid"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+// runApp runs the app with the given arguments and returns what it printed.
+func runApp(t *testing.T, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+	app.Writer = out
+	t.Cleanup(func() {
+		app.Writer = nil
+	})
+	err := app.Run(append([]string{"wallet"}, args...))
+	return out.String(), err
+}
+
+func writePasswordFile(t *testing.T, password string) string {
+	path := filepath.Join(t.TempDir(), "password.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(password), 0600))
+	return path
+}
+
+// writeRandomKeystore writes a keystore of a new random key to the directory and
+// returns its public key.
+func writeRandomKeystore(t *testing.T, dir, password string) string {
+	encryptor := keystorev4.New()
+	id, err := uuid.NewRandom()
+	require.NoError(t, err)
+	secretKey, err := bls.RandKey()
+	require.NoError(t, err)
+	cryptoFields, err := encryptor.Encrypt(secretKey.Marshal(), password)
+	require.NoError(t, err)
+	pubKey := secretKey.PublicKey().Marshal()
+	require.NoError(t, wallet.WriteKeystoresToDir(dir, []*wallet.Keystore{{
+		Crypto:  cryptoFields,
+		Pubkey:  fmt.Sprintf("%x", pubKey),
+		ID:      id.String(),
+		Version: encryptor.Version(),
+		Name:    encryptor.Name(),
+	}}))
+	return fmt.Sprintf("%#x", pubKey)
+}
+
+func TestApp_ImportedWallet(t *testing.T) {
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	global := []string{
+		"--wallet-dir", walletDir,
+		"--wallet-password-file", writePasswordFile(t, "Passw0rdz2020!"),
+		"--keystore-kdf", "fast",
+	}
+	run := func(args ...string) (string, error) {
+		return runApp(t, append(append([]string{}, global...), args...)...)
+	}
+
+	_, err := run("create")
+	require.NoError(t, err)
+	keysDir := filepath.Join(t.TempDir(), "keys")
+	pubKey := writeRandomKeystore(t, keysDir, "keystores")
+	out, err := run("import", "--keys-dir", keysDir, "--keystores-password-file", writePasswordFile(t, "keystores"))
+	require.NoError(t, err)
+	require.Equal(t, true, strings.Contains(out, pubKey))
+
+	out, err = run("--json", "list")
+	require.NoError(t, err)
+	listed := &listResult{}
+	require.NoError(t, json.Unmarshal([]byte(out), listed))
+	require.Equal(t, 1, len(listed.Accounts))
+	require.Equal(t, pubKey, listed.Accounts[0].PublicKey)
+	require.Equal(t, false, listed.Accounts[0].Disabled)
+	require.NotEqual(t, "", listed.Accounts[0].CreatedAt)
+
+	signingArgs := []string{"--public-key", pubKey, "--epoch", "2", "--slot", "64", "--proposer-index", "5454"}
+	signature, err := run(append([]string{"sign", "--audit-log"}, signingArgs...)...)
+	require.NoError(t, err)
+	signature = strings.TrimSpace(signature)
+	_, err = run(append([]string{"verify", "--signature", signature}, signingArgs...)...)
+	require.NoError(t, err)
+	_, err = run(append([]string{"verify", "--signature", signature, "--encoding", "ssz"}, signingArgs...)...)
+	require.NotNil(t, err)
+	auditLog, err := ioutil.ReadFile(filepath.Join(walletDir, wallet.AuditLogFileName))
+	require.NoError(t, err)
+	require.Equal(t, true, strings.Contains(string(auditLog), signature))
+
+	// Signing another slot info for the slot is refused unless explicitly unprotected.
+	conflictingArgs := []string{"--public-key", pubKey, "--epoch", "2", "--slot", "64", "--proposer-index", "5455"}
+	_, err = run(append([]string{"sign"}, conflictingArgs...)...)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+	_, err = run(append([]string{"sign", "--no-slashing-protection"}, conflictingArgs...)...)
+	require.NoError(t, err)
+
+	_, err = run("disable", "--public-keys", pubKey)
+	require.NoError(t, err)
+	_, err = run(append([]string{"sign"}, signingArgs...)...)
+	require.NotNil(t, err)
+	_, err = run("enable", "--public-keys", pubKey)
+	require.NoError(t, err)
+
+	exportDir := filepath.Join(t.TempDir(), "export")
+	_, err = run(
+		"export", "--public-keys", pubKey, "--output-dir", exportDir,
+		"--export-password-file", writePasswordFile(t, "export"),
+	)
+	require.NoError(t, err)
+	exported, err := wallet.ReadKeystoresFromDir(exportDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(exported))
+
+	// Accounts are only deleted once archived, unless asked not to archive them, and
+	// archives are never overwritten.
+	_, err = run("delete", "--public-keys", pubKey)
+	require.ErrorContains(t, "--output-dir or --no-archive is required", err)
+	_, err = run("delete", "--public-keys", pubKey, "--output-dir", exportDir, "--no-archive")
+	require.ErrorContains(t, "cannot be combined", err)
+	_, err = run("delete", "--public-keys", pubKey, "--output-dir", exportDir)
+	require.ErrorContains(t, "none were deleted", err)
+	require.Equal(t, true, errors.Is(err, wallet.ErrKeystoreFileExists))
+	out, err = run("list")
+	require.NoError(t, err)
+	require.Equal(t, true, strings.Contains(out, pubKey))
+
+	deleteDir := filepath.Join(t.TempDir(), "deleted")
+	_, err = run("delete", "--public-keys", pubKey, "--output-dir", deleteDir)
+	require.NoError(t, err)
+	deleted, err := wallet.ReadKeystoresFromDir(deleteDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(deleted))
+	out, err = run("list")
+	require.NoError(t, err)
+	require.Equal(t, "No accounts\n", out)
+}
+
+func TestApp_DerivedWallet(t *testing.T) {
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	passwordFile := writePasswordFile(t, "Passw0rdz2020!")
+	global := []string{"--wallet-dir", walletDir, "--wallet-password-file", passwordFile, "--keystore-kdf", "fast"}
+
+	// No wallet is left behind when the mnemonic cannot be used.
+	mnemonicFile := writePasswordFile(t, "not a mnemonic")
+	_, err := runApp(t, append(global, "create", "--keymanager-kind", "derived", "--mnemonic-file", mnemonicFile)...)
+	require.ErrorContains(t, "valid mnemonic", err)
+	exists, err := wallet.Exists(walletDir)
+	require.NoError(t, err)
+	require.Equal(t, false, exists)
+
+	out, err := runApp(t, append(global, "--json", "create", "--keymanager-kind", "derived", "--num-accounts", "2")...)
+	require.NoError(t, err)
+	created := &createResult{}
+	require.NoError(t, json.Unmarshal([]byte(out), created))
+	require.Equal(t, 2, len(created.PublicKeys))
+	require.Equal(t, 24, len(strings.Fields(created.Mnemonic)))
+
+	// The imported-only commands refuse derived wallets.
+	_, err = runApp(t, append(global, "disable", "--public-keys", created.PublicKeys[0])...)
+	require.ErrorContains(t, "derived wallets do not manage imported accounts", err)
+
+	// Without --keystore-kdf, the seed is re-encrypted with the KDF the wallet was created with.
+	newPasswordFile := writePasswordFile(t, "NewPassw0rdz2020!")
+	_, err = runApp(t,
+		"--wallet-dir", walletDir, "--wallet-password-file", passwordFile,
+		"change-password", "--new-wallet-password-file", newPasswordFile,
+	)
+	require.NoError(t, err)
+	encodedSeed, err := ioutil.ReadFile(filepath.Join(walletDir, wallet.Derived.String(), wallet.EncryptedSeedFileName))
+	require.NoError(t, err)
+	seed := &wallet.SeedConfig{}
+	require.NoError(t, json.Unmarshal(encodedSeed, seed))
+	kdfParams := seed.Crypto["kdf"].(map[string]interface{})["params"].(map[string]interface{})
+	require.Equal(t, float64(wallet.FastKDFParams().C), kdfParams["c"])
+	_, err = runApp(t, append(global, "list")...)
+	require.NotNil(t, err)
+	out, err = runApp(t, "--wallet-dir", walletDir, "--wallet-password-file", newPasswordFile, "--keystore-kdf", "fast", "list")
+	require.NoError(t, err)
+	require.Equal(t, true, strings.Contains(out, created.PublicKeys[1]))
+}
+
+func TestApp_BLSBackend(t *testing.T) {
+	defaultBackend := bls.BackendName()
+	if defaultBackend == "" {
+		t.Skip("Default BLS backend is not available")
+	}
+	t.Cleanup(func() {
+		require.NoError(t, bls.UseBackend(defaultBackend))
+	})
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	global := []string{
+		"--wallet-dir", walletDir,
+		"--wallet-password-file", writePasswordFile(t, "Passw0rdz2020!"),
+		"--keystore-kdf", "fast",
+	}
+	out, err := runApp(t, append(global, "--json", "create", "--keymanager-kind", "derived")...)
+	require.NoError(t, err)
+	created := &createResult{}
+	require.NoError(t, json.Unmarshal([]byte(out), created))
+	signingArgs := []string{"--public-key", created.PublicKeys[0], "--epoch", "1", "--slot", "32", "--proposer-index", "7"}
+
+	// Signatures of one backend are verified by the other.
+	signature, err := runApp(t, append(append(global, "--bls-backend", purego.Name, "sign"), signingArgs...)...)
+	require.NoError(t, err)
+	require.Equal(t, purego.Name, bls.BackendName())
+	require.NoError(t, bls.UseBackend(defaultBackend))
+	_, err = runApp(t, append(append(global, "verify", "--signature", strings.TrimSpace(signature)), signingArgs...)...)
+	require.NoError(t, err)
+
+	_, err = runApp(t, append(global, "--bls-backend", "unknown", "list")...)
+	require.Equal(t, true, errors.Is(err, bls.ErrUnknownBackend))
+}
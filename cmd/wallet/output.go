@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atif-konasl/eth-research/wallet"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// printResult writes the result of a command to the app writer, as indented JSON with
+// the --json flag and as text otherwise.
+func printResult(cliCtx *cli.Context, result fmt.Stringer) error {
+	if cliCtx.GlobalBool(JSONFlag.Name) {
+		encoded, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cliCtx.App.Writer, string(encoded))
+		return err
+	}
+	_, err := fmt.Fprint(cliCtx.App.Writer, result.String())
+	return err
+}
+
+type createResult struct {
+	WalletDir      string   `json:"wallet_dir"`
+	KeymanagerKind string   `json:"keymanager_kind"`
+	PublicKeys     []string `json:"public_keys,omitempty"`
+	// Mnemonic is only set when it was generated, and never printed again.
+	Mnemonic string `json:"mnemonic,omitempty"`
+}
+
+func (r *createResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Created %s wallet in %s\n", r.KeymanagerKind, r.WalletDir)
+	for _, pubKey := range r.PublicKeys {
+		fmt.Fprintf(&b, "%s\n", pubKey)
+	}
+	if r.Mnemonic != "" {
+		fmt.Fprintf(&b, "Write down the mnemonic of the wallet, it is needed to recover it:\n%s\n", r.Mnemonic)
+	}
+	return b.String()
+}
+
+type accountResult struct {
+	Index     int    `json:"index"`
+	PublicKey string `json:"public_key"`
+	Name      string `json:"name,omitempty"`
+	Disabled  bool   `json:"disabled"`
+	// CreatedAt is empty when the creation time of the account is unknown.
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+func newAccountResult(account *wallet.Account) *accountResult {
+	result := &accountResult{
+		Index:     account.Index,
+		PublicKey: fmt.Sprintf("%#x", account.PublicKey),
+		Name:      account.Name,
+		Disabled:  account.Disabled,
+	}
+	if !account.CreatedAt.IsZero() {
+		result.CreatedAt = account.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return result
+}
+
+type listResult struct {
+	Accounts []*accountResult `json:"accounts"`
+}
+
+func (r *listResult) String() string {
+	if len(r.Accounts) == 0 {
+		return "No accounts\n"
+	}
+	var b strings.Builder
+	for _, account := range r.Accounts {
+		fmt.Fprintf(&b, "%d\t%s", account.Index, account.PublicKey)
+		if account.Name != "" {
+			fmt.Fprintf(&b, "\t%s", account.Name)
+		}
+		if account.CreatedAt != "" {
+			fmt.Fprintf(&b, "\tcreated %s", account.CreatedAt)
+		}
+		if account.Disabled {
+			b.WriteString("\tdisabled")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type accountsResult struct {
+	Action     string   `json:"action"`
+	PublicKeys []string `json:"public_keys"`
+	OutputDir  string   `json:"output_dir,omitempty"`
+}
+
+func (r *accountsResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d account(s)", strings.Title(r.Action), len(r.PublicKeys))
+	if r.OutputDir != "" {
+		fmt.Fprintf(&b, ", keystores written to %s", r.OutputDir)
+	}
+	b.WriteString("\n")
+	for _, pubKey := range r.PublicKeys {
+		fmt.Fprintf(&b, "%s\n", pubKey)
+	}
+	return b.String()
+}
+
+type changePasswordResult struct {
+	WalletDir string `json:"wallet_dir"`
+	Changed   bool   `json:"changed"`
+}
+
+func (r *changePasswordResult) String() string {
+	return fmt.Sprintf("Changed the password of the wallet in %s\n", r.WalletDir)
+}
+
+type signatureResult struct {
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+func (r *signatureResult) String() string {
+	return r.Signature + "\n"
+}
+
+type verifyResult struct {
+	PublicKey string `json:"public_key"`
+	Valid     bool   `json:"valid"`
+}
+
+func (r *verifyResult) String() string {
+	return fmt.Sprintf("Valid signature by %s\n", r.PublicKey)
+}
+
+func hexPublicKeys(pubKeys [][48]byte) []string {
+	hexKeys := make([]string, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		hexKeys[i] = fmt.Sprintf("%#x", pubKey)
+	}
+	return hexKeys
+}
@@ -76,6 +76,29 @@ func WriteFile(file string, data []byte) error {
 	return ioutil.WriteFile(expanded, data, 0600)
 }
 
+// WriteNewFile writes data to a file with the permissions of WriteFile, failing with an
+// error satisfying os.IsExist if the file already exists instead of replacing it.
+func WriteNewFile(file string, data []byte) error {
+	expanded, err := ExpandPath(file)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(expanded, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(expanded)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(expanded)
+		return err
+	}
+	return nil
+}
+
 // HomeDir for a user.
 func HomeDir() string {
 	if home := os.Getenv("HOME"); home != "" {
@@ -120,6 +143,20 @@ func FileExists(filename string) bool {
 	return info != nil && !info.IsDir()
 }
 
+// HasReadWritePermissions checks if the file at a path has 0600 permissions, readable
+// and writable by its owner only, as required for files holding secrets.
+func HasReadWritePermissions(itemPath string) (bool, error) {
+	expanded, err := ExpandPath(itemPath)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(expanded)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode() == 0600, nil
+}
+
 // ReadFileAsBytes expands a file name's absolute path and reads it as bytes from disk.
 func ReadFileAsBytes(filename string) ([]byte, error) {
 	filePath, err := ExpandPath(filename)
@@ -248,3 +285,47 @@ func DirFiles(dir string) ([]string, error) {
 	}
 	return files, nil
 }
+
+// WriteFileAtomically writes data to a temporary file next to the destination
+// and renames it into place, so readers never observe a partially written file.
+// Like WriteFile, it refuses to replace an existing file without 0600 permissions.
+func WriteFileAtomically(file string, data []byte) error {
+	expanded, err := ExpandPath(file)
+	if err != nil {
+		return err
+	}
+	if FileExists(expanded) {
+		info, err := os.Stat(expanded)
+		if err != nil {
+			return err
+		}
+		if info.Mode() != 0600 {
+			return errors.New("file already exists without proper 0600 permissions")
+		}
+	}
+	// Temporary files are created with 0600 permissions.
+	tmpFile, err := ioutil.TempFile(filepath.Dir(expanded), filepath.Base(expanded)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, expanded); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrapf(err, "could not move temporary file into place: %s", expanded)
+	}
+	return nil
+}
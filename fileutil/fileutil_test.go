@@ -100,6 +100,66 @@ func TestWriteFile_OK(t *testing.T) {
 	assert.Equal(t, true, exists)
 }
 
+func TestWriteNewFile(t *testing.T) {
+	someFileName := filepath.Join(t.TempDir(), "somefile.txt")
+	require.NoError(t, fileutil.WriteNewFile(someFileName, []byte("hi")))
+	info, err := os.Stat(someFileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode())
+
+	err = fileutil.WriteNewFile(someFileName, []byte("hello"))
+	assert.Equal(t, true, os.IsExist(err))
+	content, err := ioutil.ReadFile(someFileName)
+	require.NoError(t, err)
+	assert.DeepEqual(t, []byte("hi"), content)
+}
+
+func TestWriteFileAtomically_AlreadyExists_WrongPermissions(t *testing.T) {
+	dirName := t.TempDir() + "somedir"
+	err := os.MkdirAll(dirName, os.ModePerm)
+	require.NoError(t, err)
+	someFileName := filepath.Join(dirName, "somefile.txt")
+	require.NoError(t, ioutil.WriteFile(someFileName, []byte("hi"), os.ModePerm))
+	err = fileutil.WriteFileAtomically(someFileName, []byte("hello"))
+	assert.ErrorContains(t, "already exists without proper 0600 permissions", err)
+}
+
+func TestWriteFileAtomically_OK(t *testing.T) {
+	dirName := t.TempDir() + "somedir"
+	err := os.MkdirAll(dirName, os.ModePerm)
+	require.NoError(t, err)
+	someFileName := filepath.Join(dirName, "somefile.txt")
+	require.NoError(t, fileutil.WriteFile(someFileName, []byte("hi")))
+	require.NoError(t, fileutil.WriteFileAtomically(someFileName, []byte("hello")))
+
+	content, err := ioutil.ReadFile(someFileName)
+	require.NoError(t, err)
+	assert.DeepEqual(t, []byte("hello"), content)
+	info, err := os.Stat(someFileName)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode())
+	files, err := ioutil.ReadDir(dirName)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(files), "Temporary file was left behind")
+}
+
+func TestHasReadWritePermissions(t *testing.T) {
+	dirName := t.TempDir()
+	someFileName := filepath.Join(dirName, "somefile.txt")
+	require.NoError(t, ioutil.WriteFile(someFileName, []byte("hi"), os.ModePerm))
+	ok, err := fileutil.HasReadWritePermissions(someFileName)
+	require.NoError(t, err)
+	assert.Equal(t, false, ok)
+
+	require.NoError(t, os.Chmod(someFileName, 0600))
+	ok, err = fileutil.HasReadWritePermissions(someFileName)
+	require.NoError(t, err)
+	assert.Equal(t, true, ok)
+
+	_, err = fileutil.HasReadWritePermissions(filepath.Join(dirName, "missing.txt"))
+	assert.Equal(t, true, os.IsNotExist(err))
+}
+
 func TestCopyFile(t *testing.T) {
 	fName := t.TempDir() + "testfile"
 	err := ioutil.WriteFile(fName, []byte{1, 2, 3}, 0600)
@@ -1,24 +1,33 @@
 package main
 
 import (
+	"context"
+
 	accManager "github.com/atif-konasl/eth-research/wallet"
 )
 
 func main() {
+	ctx := context.Background()
+	walletDir := "./wallet/prysm-wallet-v2"
 	config := accManager.Config{
-		WalletDir: "./wallet/prysm-wallet-v2",
+		WalletDir: walletDir,
 		KeymanagerKind: accManager.Kind(0),
-		WalletPassword: "Konasl@123",
+		PasswordSource: accManager.DefaultPasswordSource(walletDir),
 	}
 	log.Info("opening bls keystore wallet. directory: ", config.WalletDir)
-	wallet, err := accManager.OpenWallet(nil, &config)
+	wallet, err := accManager.OpenWallet(ctx, &config)
 	if err != nil {
 		log.Errorf("failed to open wallet: %v", err)
 		return
 	}
+	defer func() {
+		if err := wallet.Close(); err != nil {
+			log.Errorf("failed to close wallet: %v", err)
+		}
+	}()
 
 	log.Info("setting up key manager with wallet....")
-	keyManager, err := accManager.NewKeymanager(wallet)
+	keyManager, err := accManager.NewKeymanager(ctx, wallet)
 	if err != nil {
 		log.Errorf("failed to initiate key manager: %v", err)
 		return
@@ -26,20 +35,32 @@ func main() {
 
 	slotInfo := accManager.NewSlotInfo(2, 64, 5454)
 	log.Info("creating dummy slot info for signing: ", slotInfo)
+	// Devnet domain, with a zero genesis fork version and genesis validators root.
+	domain := accManager.ComputeDomain(accManager.DomainSlotInfo, [4]byte{}, [32]byte{})
+
+	pubKeys, err := keyManager.FetchValidatingPublicKeys(ctx)
+	if err != nil {
+		log.Errorf("failed to fetch validating public keys: %v", err)
+		return
+	}
+	if len(pubKeys) < 2 {
+		log.Errorf("wallet needs at least 2 accounts, found %d", len(pubKeys))
+		return
+	}
 
-	signerPubKeyIndex := uint64(1)
-	signature, err := keyManager.Sign(slotInfo, signerPubKeyIndex)
+	signerPubKey := pubKeys[1]
+	signature, err := keyManager.Sign(slotInfo, domain, signerPubKey)
 	if err != nil {
-		log.Errorf("failed to verify signature with publicKeyIndex: %d error: %v", signerPubKeyIndex, err)
+		log.Errorf("failed to generate signature with publicKey: %#x error: %v", signerPubKey, err)
 		return
 	}
-	log.Info("successfully generate signature: %s with publicKeyIndex: %d", signature.HexString(), signerPubKeyIndex)
+	log.Infof("successfully generate signature: %s with publicKey: %#x", signature.HexString(), signerPubKey)
 
-	verifierPubKeyIndex := uint64(0)
-	err = keyManager.VerifySignature(slotInfo, verifierPubKeyIndex, signature)
+	verifierPubKey := pubKeys[0]
+	err = keyManager.VerifySignature(slotInfo, domain, verifierPubKey, signature)
 	if err != nil {
-		log.Errorf("failed to verify signature: %s with publicKeyIndex: %d error: %v", signature.HexString(), verifierPubKeyIndex, err)
+		log.Errorf("failed to verify signature: %s with publicKey: %#x error: %v", signature.HexString(), verifierPubKey, err)
 		return
 	}
-	log.Infof("successfully verify the signature: %s with publicKeyIndex: %d", signature.HexString(), verifierPubKeyIndex)
-}
\ No newline at end of file
+	log.Infof("successfully verify the signature: %s with publicKey: %#x", signature.HexString(), verifierPubKey)
+}
@@ -0,0 +1,293 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuditLogFileName of the audit log inside its directory, usually the wallet directory.
+	AuditLogFileName = "audit.log"
+	// AuditLogHeadFileName of the head of the audit log, kept next to it.
+	AuditLogHeadFileName = "audit.head.json"
+)
+
+// AuditRecord is a line of the audit log, recording a signing request and its result.
+// Every record holds the hash of the previous one, so records cannot be removed,
+// reordered or altered without breaking the chain.
+type AuditRecord struct {
+	Sequence      uint64 `json:"sequence"`
+	Time          string `json:"time"`
+	PublicKey     string `json:"public_key"`
+	Epoch         uint64 `json:"epoch"`
+	Slot          uint64 `json:"slot"`
+	ProposerIndex uint64 `json:"proposer_index"`
+	// SigningRoot is empty when the request failed before it was computed.
+	SigningRoot string `json:"signing_root,omitempty"`
+	// Signature is set if the request was signed, Error otherwise.
+	Signature string `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+	PrevHash  string `json:"prev_hash"`
+	Hash      string `json:"hash,omitempty"`
+}
+
+// AuditLogHead identifies the last record of an audit log, which allows detecting records
+// removed from the end of the log. The head is stored next to the log after every record
+// and checked when the log is opened; a copy kept elsewhere also detects a log rewritten
+// along with its head file.
+type AuditLogHead struct {
+	Records uint64 `json:"records"`
+	Hash    string `json:"hash"`
+}
+
+// AuditLog is an append-only, hash-chained log of signing requests, safe for concurrent use.
+type AuditLog struct {
+	lock     sync.Mutex
+	file     *os.File
+	path     string
+	headPath string
+	head     AuditLogHead
+}
+
+// NewAuditLog opens, creating it if needed, the audit log in the given directory. The
+// chain of the existing records is verified against the stored head first, and nothing
+// is appended to a log which was tampered with or truncated. A last record which is
+// incomplete, as left by a crash while it was written, was never part of the head and
+// is removed.
+func NewAuditLog(dirPath string) (*AuditLog, error) {
+	hasDir, err := fileutil.HasDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDir {
+		if err := fileutil.MkdirAll(dirPath); err != nil {
+			return nil, errors.Wrapf(err, "could not create path: %s", dirPath)
+		}
+	}
+	path := filepath.Join(dirPath, AuditLogFileName)
+	headPath := filepath.Join(dirPath, AuditLogHeadFileName)
+	recorded, err := readAuditLogHead(headPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open audit log")
+	}
+	l, err := openAuditLog(f, path, headPath, recorded)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "could not verify audit log %s", path)
+	}
+	return l, nil
+}
+
+// openAuditLog verifies the opened audit log file against the recorded head, nil if no
+// head was stored yet, and removes an incomplete last record.
+func openAuditLog(f *os.File, path, headPath string, recorded *AuditLogHead) (*AuditLog, error) {
+	head, size, err := verifyAuditRecords(f, recorded, true /* trimIncomplete */)
+	if err != nil {
+		return nil, err
+	}
+	if recorded == nil && head.Records > 0 {
+		return nil, errors.Wrapf(ErrAuditLogTruncated, "head file %s is missing", headPath)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > size {
+		log.WithField("path", path).Warn("Removing incomplete last record of audit log")
+		if err := f.Truncate(size); err != nil {
+			return nil, errors.Wrap(err, "could not remove incomplete audit record")
+		}
+	}
+	l := &AuditLog{file: f, path: path, headPath: headPath, head: *head}
+	if err := l.writeHead(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// readAuditLogHead reads the stored head of an audit log, nil if there is none.
+func readAuditLogHead(headPath string) (*AuditLogHead, error) {
+	encoded, err := ioutil.ReadFile(headPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not read audit log head")
+	}
+	head := &AuditLogHead{}
+	if err := json.Unmarshal(encoded, head); err != nil {
+		return nil, errors.Wrapf(ErrAuditLogTampered, "head file cannot be decoded: %v", err)
+	}
+	return head, nil
+}
+
+// writeHead stores the head of the log next to it. The caller must hold l.lock.
+func (l *AuditLog) writeHead() error {
+	encoded, err := json.Marshal(&l.head)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileAtomically(l.headPath, encoded); err != nil {
+		return errors.Wrap(err, "could not write audit log head")
+	}
+	return nil
+}
+
+// Close the audit log. Closing it more than once is a no-op.
+func (l *AuditLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Path of the audit log file.
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+// Head of the audit log, including every record appended so far.
+func (l *AuditLog) Head() AuditLogHead {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.head
+}
+
+// recordSigning appends the result of a signing request to the log and syncs it to disk.
+func (l *AuditLog) recordSigning(
+	pubKey [48]byte, slotInfo *SlotInfo, signingRoot []byte, signature bls.Signature, signErr error,
+) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	record := &AuditRecord{
+		Sequence:  l.head.Records,
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		PublicKey: fmt.Sprintf("%#x", pubKey),
+		PrevHash:  l.head.Hash,
+	}
+	if slotInfo != nil {
+		record.Epoch, record.Slot, record.ProposerIndex = slotInfo.Epoch, slotInfo.Slot, slotInfo.ProposerIndex
+	}
+	if signingRoot != nil {
+		record.SigningRoot = fmt.Sprintf("%#x", signingRoot)
+	}
+	if signErr != nil {
+		record.Error = signErr.Error()
+	} else if signature != nil {
+		record.Signature = fmt.Sprintf("%#x", signature.Marshal())
+	}
+	hash, err := auditRecordHash(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(encoded, '\n')); err != nil {
+		return errors.Wrap(err, "could not write audit record")
+	}
+	if err := l.file.Sync(); err != nil {
+		return errors.Wrap(err, "could not sync audit log")
+	}
+	l.head = AuditLogHead{Records: record.Sequence + 1, Hash: hash}
+	return l.writeHead()
+}
+
+// VerifyAuditLog reads an audit log and checks that every record is chained to the
+// previous one, from the first record on. A head recorded earlier, if given, must be
+// part of the chain, which detects records removed from the end of the log. Returns
+// the head of the log.
+func VerifyAuditLog(r io.Reader, recorded *AuditLogHead) (*AuditLogHead, error) {
+	head, _, err := verifyAuditRecords(r, recorded, false /* trimIncomplete */)
+	return head, err
+}
+
+// verifyAuditRecords is VerifyAuditLog, which also returns the size of the verified
+// records. An incomplete last record is left out instead of refused if trimIncomplete
+// is set.
+func verifyAuditRecords(r io.Reader, recorded *AuditLogHead, trimIncomplete bool) (*AuditLogHead, int64, error) {
+	head := &AuditLogHead{}
+	var size int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) != 0 && !trimIncomplete {
+				return nil, 0, errors.Wrapf(ErrAuditLogTruncated, "record %d is incomplete", head.Records)
+			}
+			break
+		} else if err != nil {
+			return nil, 0, errors.Wrap(err, "could not read audit log")
+		}
+		record := &AuditRecord{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(record); err != nil {
+			return nil, 0, errors.Wrapf(ErrAuditLogTampered, "record %d cannot be decoded: %v", head.Records, err)
+		}
+		if record.Sequence != head.Records {
+			return nil, 0, errors.Wrapf(ErrAuditLogTampered, "record %d has sequence %d", head.Records, record.Sequence)
+		}
+		if record.PrevHash != head.Hash {
+			return nil, 0, errors.Wrapf(ErrAuditLogTampered, "record %d is not chained to the previous record", head.Records)
+		}
+		hash := record.Hash
+		record.Hash = ""
+		expected, err := auditRecordHash(record)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hash != expected {
+			return nil, 0, errors.Wrapf(ErrAuditLogTampered, "record %d does not match its hash", head.Records)
+		}
+		head.Records++
+		head.Hash = hash
+		size += int64(len(line))
+		if recorded != nil && head.Records == recorded.Records && head.Hash != recorded.Hash {
+			return nil, 0, errors.Wrapf(ErrAuditLogTampered, "record %d does not match the recorded head", head.Records-1)
+		}
+	}
+	if recorded != nil && head.Records < recorded.Records {
+		return nil, 0, errors.Wrapf(
+			ErrAuditLogTruncated, "found %d records, %d were recorded", head.Records, recorded.Records,
+		)
+	}
+	return head, size, nil
+}
+
+// auditRecordHash of a record without its hash, chained to the previous record by its
+// PrevHash field.
+func auditRecordHash(record *AuditRecord) (string, error) {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+)
+
+// setupAuditLog signs a few slot infos, one of them refused, with an audit log in use.
+func setupAuditLog(t *testing.T) (*AuditLog, [][]byte) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	store, err := slashingprotection.NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+	})
+	km.UseSlashingProtection(store)
+	auditLog, err := NewAuditLog(w.walletDir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, auditLog.Close())
+	})
+	km.UseAuditLog(auditLog)
+
+	signature, err := km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+	_, err = km.SignBatch(NewSlotInfo(2, 65, 5454), testDomain, [][48]byte{pubKey})
+	require.NoError(t, err)
+
+	encoded, err := ioutil.ReadFile(auditLog.Path())
+	require.NoError(t, err)
+	lines := bytes.SplitAfter(encoded, []byte("\n"))
+	lines = lines[:len(lines)-1]
+	require.Equal(t, 3, len(lines))
+	require.Equal(t, true, strings.Contains(string(lines[0]), fmt.Sprintf("%#x", signature.Marshal())))
+	require.Equal(t, true, strings.Contains(string(lines[1]), slashingprotection.ErrDoubleProposal.Error()))
+	return auditLog, lines
+}
+
+func TestAuditLog_Verify(t *testing.T) {
+	auditLog, lines := setupAuditLog(t)
+	head, err := VerifyAuditLog(bytes.NewReader(bytes.Join(lines, nil)), nil)
+	require.NoError(t, err)
+	require.Equal(t, auditLog.Head(), *head)
+	require.Equal(t, uint64(3), head.Records)
+
+	// Reopening the log continues the chain.
+	require.NoError(t, auditLog.Close())
+	reopened, err := NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, reopened.Close())
+	}()
+	require.Equal(t, *head, reopened.Head())
+	require.NoError(t, reopened.recordSigning([48]byte{1}, NewSlotInfo(2, 66, 5454), nil, nil, ErrUnknownPublicKey))
+	f, err := ioutil.ReadFile(reopened.Path())
+	require.NoError(t, err)
+	reopenedHead, err := VerifyAuditLog(bytes.NewReader(f), head)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), reopenedHead.Records)
+}
+
+func TestAuditLog_Tampering(t *testing.T) {
+	auditLog, lines := setupAuditLog(t)
+	head := auditLog.Head()
+	join := func(lines ...[]byte) *bytes.Reader {
+		return bytes.NewReader(bytes.Join(lines, nil))
+	}
+
+	altered := bytes.Replace(lines[1], []byte(`"slot":64`), []byte(`"slot":63`), 1)
+	_, err := VerifyAuditLog(join(lines[0], altered, lines[2]), nil)
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTampered))
+
+	_, err = VerifyAuditLog(join(lines[0], lines[2]), nil)
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTampered))
+	_, err = VerifyAuditLog(join(lines[1], lines[2]), nil)
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTampered))
+
+	// Records removed from the end are only detected given an earlier head.
+	_, err = VerifyAuditLog(join(lines[0], lines[1]), nil)
+	require.NoError(t, err)
+	_, err = VerifyAuditLog(join(lines[0], lines[1]), &head)
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTruncated))
+	_, err = VerifyAuditLog(join(lines[0], lines[1], lines[2][:len(lines[2])/2]), nil)
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTruncated))
+
+	require.NoError(t, ioutil.WriteFile(auditLog.Path(), bytes.Join([][]byte{lines[0], altered, lines[2]}, nil), 0600))
+	_, err = NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTampered))
+
+	// Opening the log checks it against the stored head.
+	require.NoError(t, ioutil.WriteFile(auditLog.Path(), bytes.Join(lines[:2], nil), 0600))
+	_, err = NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTruncated))
+	require.NoError(t, ioutil.WriteFile(auditLog.Path(), nil, 0600))
+	_, err = NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTruncated))
+	require.NoError(t, ioutil.WriteFile(auditLog.Path(), bytes.Join(lines, nil), 0600))
+	require.NoError(t, os.Remove(filepath.Join(filepath.Dir(auditLog.Path()), AuditLogHeadFileName)))
+	_, err = NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.Equal(t, true, errors.Is(err, ErrAuditLogTruncated))
+}
+
+func TestAuditLog_IncompleteRecord(t *testing.T) {
+	auditLog, lines := setupAuditLog(t)
+	head := auditLog.Head()
+	require.NoError(t, auditLog.Close())
+
+	// A record partly written when the process crashed is removed on open.
+	incomplete := append(bytes.Join(lines, nil), lines[2][:len(lines[2])/2]...)
+	require.NoError(t, ioutil.WriteFile(auditLog.Path(), incomplete, 0600))
+	reopened, err := NewAuditLog(filepath.Dir(auditLog.Path()))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, reopened.Close())
+	}()
+	require.Equal(t, head, reopened.Head())
+	require.NoError(t, reopened.recordSigning([48]byte{1}, NewSlotInfo(2, 66, 5454), nil, nil, ErrUnknownPublicKey))
+	f, err := ioutil.ReadFile(reopened.Path())
+	require.NoError(t, err)
+	verified, err := VerifyAuditLog(bytes.NewReader(f), &head)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), verified.Records)
+}
@@ -0,0 +1,344 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// BackupManifestFileName of the manifest inside a backup archive.
+	BackupManifestFileName = "manifest.json"
+	// BackupKeystoresDir inside a backup archive, holding one EIP-2335 keystore per account.
+	BackupKeystoresDir = "keystores"
+	// BackupVersion of the archives written by Backup. Version 1 archives carried no MAC.
+	BackupVersion = "2"
+	// Length of the salt of the KDF deriving the MAC key of a backup.
+	backupSaltLen = 32
+)
+
+// BackupManifest describes the content of a backup archive. Its checksum is the
+// fileutil.HashDir hash of the keystores directory, so an archive whose keystores were
+// corrupted is refused on restore. Its MAC is an HMAC-SHA256 of the manifest and that
+// checksum, keyed with the backup password through the KDF, so an archive altered by
+// anyone not knowing the password is refused as well.
+type BackupManifest struct {
+	Version        string     `json:"version"`
+	CreatedAt      time.Time  `json:"created_at"`
+	KeymanagerKind string     `json:"keymanager_kind"`
+	PublicKeys     []string   `json:"public_keys"`
+	Checksum       string     `json:"checksum"`
+	KDF            *BackupKDF `json:"kdf"`
+	MAC            string     `json:"mac"`
+}
+
+// BackupKDF derives the MAC key of a backup archive from its password.
+type BackupKDF struct {
+	Function string `json:"function"`
+	C        int    `json:"c,omitempty"`
+	N        int    `json:"n,omitempty"`
+	R        int    `json:"r,omitempty"`
+	P        int    `json:"p,omitempty"`
+	Salt     string `json:"salt"`
+}
+
+// Backup packages the selected accounts of the keymanager, disabled ones included, as
+// EIP-2335 keystores encrypted with the given password into a zip archive, along with
+// a manifest.
+func (km *Keymanager) Backup(_ context.Context, pubKeys [][48]byte, password string) ([]byte, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no accounts selected for backup")
+	}
+	keystores, err := km.ExportKeystores(pubKeys, password, true /* includeDisabled */)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not export keystores")
+	}
+
+	// Lay out the keystores on disk to compute their checksum.
+	tmpDir, err := ioutil.TempDir("", "wallet-backup")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.WithError(err).Error("Could not remove temporary backup directory")
+		}
+	}()
+	keystoresDir := filepath.Join(tmpDir, BackupKeystoresDir)
+	if err := WriteKeystoresToDir(keystoresDir, keystores); err != nil {
+		return nil, err
+	}
+	checksum, err := fileutil.HashDir(keystoresDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not compute checksum of keystores")
+	}
+	kdfParams := km.wallet.kdfParams
+	if kdfParams == nil {
+		kdfParams = DefaultKDFParams()
+	}
+	salt := make([]byte, backupSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	manifest := &BackupManifest{
+		Version:        BackupVersion,
+		CreatedAt:      time.Now().UTC(),
+		KeymanagerKind: km.wallet.keymanagerKind.String(),
+		PublicKeys:     make([]string, len(keystores)),
+		Checksum:       checksum,
+		KDF: &BackupKDF{
+			Function: kdfParams.Function.String(),
+			C:        kdfParams.C,
+			N:        kdfParams.N,
+			R:        kdfParams.R,
+			P:        kdfParams.P,
+			Salt:     hex.EncodeToString(salt),
+		},
+	}
+	for i, keystore := range keystores {
+		manifest.PublicKeys[i] = keystore.Pubkey
+	}
+	mac, err := backupMAC(manifest, checksum, password)
+	if err != nil {
+		return nil, err
+	}
+	manifest.MAC = hex.EncodeToString(mac)
+	encodedManifest, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	if err := writeZipFile(zipWriter, BackupManifestFileName, encodedManifest); err != nil {
+		return nil, err
+	}
+	keystoreFiles, err := fileutil.DirFiles(keystoresDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range keystoreFiles {
+		data, err := fileutil.ReadFileAsBytes(filepath.Join(keystoresDir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(zipWriter, path.Join(BackupKeystoresDir, name), data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "could not write backup archive")
+	}
+	log.WithField("numAccounts", len(keystores)).Info("Backed up accounts")
+	return buf.Bytes(), nil
+}
+
+// RestoreBackup imports the accounts of a backup archive into the keymanager,
+// decrypting its keystores with the password they were backed up with. Returns the
+// public keys of the restored accounts.
+func (km *Keymanager) RestoreBackup(ctx context.Context, archive []byte, password string) ([][48]byte, error) {
+	_, keystores, err := ReadBackup(archive, password)
+	if err != nil {
+		return nil, err
+	}
+	passwords := make([]string, len(keystores))
+	pubKeys := make([][48]byte, len(keystores))
+	for i, keystore := range keystores {
+		passwords[i] = password
+		pubKey, err := hexDecodePubKey(keystore.Pubkey)
+		if err != nil {
+			return nil, err
+		}
+		pubKeys[i] = pubKey
+	}
+	if err := km.ImportKeystores(ctx, keystores, passwords); err != nil {
+		return nil, errors.Wrap(err, "could not import keystores from backup")
+	}
+	log.WithFields(logrus.Fields{
+		"numAccounts": len(keystores),
+		"walletDir":   km.wallet.walletDir,
+	}).Info("Restored accounts from backup")
+	return pubKeys, nil
+}
+
+// ReadBackup reads the manifest and keystores of a backup archive, checking the
+// keystores against the manifest checksum and public keys. The MAC of the manifest is
+// verified with the backup password, returning ErrBackupMACMismatch if the archive was
+// altered or the password is wrong.
+func ReadBackup(archive []byte, password string) (*BackupManifest, []*Keystore, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not read backup archive")
+	}
+	tmpDir, err := ioutil.TempDir("", "wallet-restore")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.WithError(err).Error("Could not remove temporary restore directory")
+		}
+	}()
+	keystoresDir := filepath.Join(tmpDir, BackupKeystoresDir)
+	if err := fileutil.MkdirAll(keystoresDir); err != nil {
+		return nil, nil, err
+	}
+	var manifest *BackupManifest
+	for _, file := range zipReader.File {
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		dir, name := path.Split(file.Name)
+		switch {
+		case file.Name == BackupManifestFileName:
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, errors.Wrap(err, "could not decode backup manifest")
+			}
+		case dir == BackupKeystoresDir+"/" && name != "":
+			// Only the base name is used, so entries cannot be written outside the directory.
+			if err := fileutil.WriteFile(filepath.Join(keystoresDir, name), data); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, fmt.Errorf("unexpected file %s in backup archive", file.Name)
+		}
+	}
+	if manifest == nil {
+		return nil, nil, errors.New("backup archive has no manifest")
+	}
+	if manifest.Version != BackupVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %q", manifest.Version)
+	}
+	checksum, err := fileutil.HashDir(keystoresDir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not compute checksum of keystores")
+	}
+	if checksum != manifest.Checksum {
+		return nil, nil, errors.Wrapf(ErrBackupChecksumMismatch, "got %s, manifest has %s", checksum, manifest.Checksum)
+	}
+	mac, err := backupMAC(manifest, checksum, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	recordedMAC, err := hex.DecodeString(manifest.MAC)
+	if err != nil || !hmac.Equal(mac, recordedMAC) {
+		return nil, nil, ErrBackupMACMismatch
+	}
+	keystores, err := ReadKeystoresFromDir(keystoresDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keystores) != len(manifest.PublicKeys) {
+		return nil, nil, fmt.Errorf(
+			"backup archive has %d keystores, manifest lists %d accounts", len(keystores), len(manifest.PublicKeys),
+		)
+	}
+	listed := make(map[string]bool, len(manifest.PublicKeys))
+	for _, pubKey := range manifest.PublicKeys {
+		listed[pubKey] = true
+	}
+	for _, keystore := range keystores {
+		if !listed[keystore.Pubkey] {
+			return nil, nil, fmt.Errorf("keystore for public key %s is not listed in the manifest", keystore.Pubkey)
+		}
+	}
+	return manifest, keystores, nil
+}
+
+// backupMAC computes the MAC of a manifest, leaving out its MAC field, and of the
+// checksum of the keystores, with a key derived from the password by the manifest KDF.
+func backupMAC(manifest *BackupManifest, checksum, password string) ([]byte, error) {
+	if manifest.KDF == nil {
+		return nil, errors.New("backup manifest has no KDF")
+	}
+	function, err := ParseKDF(manifest.KDF.Function)
+	if err != nil {
+		return nil, err
+	}
+	params := &KDFParams{
+		Function: function,
+		C:        manifest.KDF.C,
+		N:        manifest.KDF.N,
+		R:        manifest.KDF.R,
+		P:        manifest.KDF.P,
+	}
+	if err := params.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid backup KDF parameters")
+	}
+	salt, err := hex.DecodeString(manifest.KDF.Salt)
+	if err != nil || len(salt) != backupSaltLen {
+		return nil, errors.New("invalid backup KDF salt")
+	}
+	key, err := params.deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	unsigned := *manifest
+	unsigned.MAC = ""
+	encoded, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(encoded)
+	mac.Write([]byte(checksum))
+	return mac.Sum(nil), nil
+}
+
+func writeZipFile(zipWriter *zip.Writer, name string, data []byte) error {
+	f, err := zipWriter.Create(name)
+	if err != nil {
+		return errors.Wrapf(err, "could not add %s to backup archive", name)
+	}
+	if _, err := f.Write(data); err != nil {
+		return errors.Wrapf(err, "could not add %s to backup archive", name)
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s in backup archive", file.Name)
+	}
+	defer func() {
+		if err := rc.Close(); err != nil {
+			log.WithError(err).Debugf("Could not close %s in backup archive", file.Name)
+		}
+	}()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s in backup archive", file.Name)
+	}
+	return data, nil
+}
+
+func hexDecodePubKey(pubKey string) ([48]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(pubKey, "0x"))
+	if err != nil {
+		return [48]byte{}, errors.Wrapf(err, "could not decode public key %s", pubKey)
+	}
+	if len(decoded) != 48 {
+		return [48]byte{}, fmt.Errorf("public key %s is not 48 bytes", pubKey)
+	}
+	return bytesutil.ToBytes48(decoded), nil
+}
@@ -0,0 +1,182 @@
+package wallet
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func setupBackup(t *testing.T) ([]byte, []bls.SecretKey) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore1, secretKey1 := createRandomKeystore(t, "password")
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	keystore3, _ := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(
+		ctx, []*Keystore{keystore1, keystore2, keystore3}, []string{"password", "password", "password"},
+	))
+	pubKey1 := bytesutil.ToBytes48(secretKey1.PublicKey().Marshal())
+	pubKey2 := bytesutil.ToBytes48(secretKey2.PublicKey().Marshal())
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{pubKey2}))
+
+	archive, err := km.Backup(ctx, [][48]byte{pubKey1, pubKey2}, "backup password")
+	require.NoError(t, err)
+	return archive, []bls.SecretKey{secretKey1, secretKey2}
+}
+
+// rewriteArchive copies a zip archive, letting edit replace the content of its entries
+// or drop them by returning nil, and appends the extra entries.
+func rewriteArchive(
+	t *testing.T, archive []byte, edit func(name string, data []byte) []byte, extra map[string][]byte,
+) []byte {
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	for _, file := range zipReader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		data = edit(file.Name, data)
+		if data == nil {
+			continue
+		}
+		f, err := zipWriter.Create(file.Name)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+	}
+	for name, data := range extra {
+		f, err := zipWriter.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+func TestWallet_BackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	archive, secretKeys := setupBackup(t)
+
+	manifest, keystores, err := ReadBackup(archive, "backup password")
+	require.NoError(t, err)
+	require.Equal(t, BackupVersion, manifest.Version)
+	require.Equal(t, Imported.String(), manifest.KeymanagerKind)
+	require.DeepEqual(t, []string{
+		fmt.Sprintf("%x", secretKeys[0].PublicKey().Marshal()),
+		fmt.Sprintf("%x", secretKeys[1].PublicKey().Marshal()),
+	}, manifest.PublicKeys)
+	require.Equal(t, 2, len(keystores))
+
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	_, err = km.RestoreBackup(ctx, archive, "wrong password")
+	require.Equal(t, true, errors.Is(err, ErrBackupMACMismatch))
+
+	// The restored accounts are visible to the keymanager they were restored through,
+	// and kept when it writes the accounts keystore again.
+	restored, err := km.RestoreBackup(ctx, archive, "backup password")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(restored))
+	require.NoError(t, km.DisableAccounts(ctx, restored[:1]))
+	require.NoError(t, km.EnableAccounts(ctx, restored[:1]))
+	pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, restored, pubKeys)
+	km, err = NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	for _, secretKey := range secretKeys {
+		pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+		signature, err := km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+		require.NoError(t, err)
+		root := ComputeSigningRoot(NewSlotInfo(2, 64, 5454), testDomain)
+		require.DeepEqual(t, secretKey.Sign(root[:]).Marshal(), signature.Marshal())
+	}
+}
+
+func TestReadBackup_Corrupted(t *testing.T) {
+	archive, _ := setupBackup(t)
+
+	corrupted := rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		if name == BackupKeystoresDir+"/keystore-0.json" {
+			return bytes.Replace(data, []byte(`"version"`), []byte(`"version" `), 1)
+		}
+		return data
+	}, nil)
+	_, _, err := ReadBackup(corrupted, "backup password")
+	require.Equal(t, true, errors.Is(err, ErrBackupChecksumMismatch))
+
+	_, _, err = ReadBackup(archive[:len(archive)/2], "backup password")
+	require.ErrorContains(t, "could not read backup archive", err)
+}
+
+func TestReadBackup_UnexpectedFile(t *testing.T) {
+	archive, _ := setupBackup(t)
+	unchanged := func(_ string, data []byte) []byte { return data }
+	altered := rewriteArchive(t, archive, unchanged, map[string][]byte{"../keystore-9.json": []byte("{}")})
+	_, _, err := ReadBackup(altered, "backup password")
+	require.ErrorContains(t, "unexpected file ../keystore-9.json in backup archive", err)
+}
+
+func TestReadBackup_Altered(t *testing.T) {
+	archive, secretKeys := setupBackup(t)
+	removedPubKey := fmt.Sprintf("%x", secretKeys[1].PublicKey().Marshal())
+
+	// Drop the keystore of an account and fix up the manifest as the archive would
+	// have been written without it.
+	keystoresDir := filepath.Join(t.TempDir(), BackupKeystoresDir)
+	require.NoError(t, fileutil.MkdirAll(keystoresDir))
+	kept := rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		if strings.HasPrefix(name, BackupKeystoresDir+"/") && strings.Contains(string(data), removedPubKey) {
+			return nil
+		}
+		if name != BackupManifestFileName {
+			require.NoError(t, fileutil.WriteFile(filepath.Join(keystoresDir, path.Base(name)), data))
+		}
+		return data
+	}, nil)
+	checksum, err := fileutil.HashDir(keystoresDir)
+	require.NoError(t, err)
+	altered := rewriteArchive(t, kept, func(name string, data []byte) []byte {
+		if name != BackupManifestFileName {
+			return data
+		}
+		manifest := &BackupManifest{}
+		require.NoError(t, json.Unmarshal(data, manifest))
+		manifest.PublicKeys = []string{fmt.Sprintf("%x", secretKeys[0].PublicKey().Marshal())}
+		manifest.Checksum = checksum
+		encoded, err := json.Marshal(manifest)
+		require.NoError(t, err)
+		return encoded
+	}, nil)
+
+	_, _, err = ReadBackup(altered, "backup password")
+	require.Equal(t, true, errors.Is(err, ErrBackupMACMismatch))
+	km, err := NewImportedKeymanager(context.Background(), setupImportedWallet(t))
+	require.NoError(t, err)
+	_, err = km.RestoreBackup(context.Background(), altered, "backup password")
+	require.Equal(t, true, errors.Is(err, ErrBackupMACMismatch))
+	pubKeys, err := km.FetchValidatingPublicKeys(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, len(pubKeys))
+}
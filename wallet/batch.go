@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/pkg/errors"
+)
+
+// BatchSignature holds the signatures of a slot info by several accounts, in the order
+// of their public keys, and the aggregate of those signatures.
+type BatchSignature struct {
+	PublicKeys [][48]byte
+	Signatures []bls.Signature
+	Aggregate  bls.Signature
+}
+
+// SignBatch signs the signing root of the slot info in the domain with the validating
+// keys of every given public key, spreading the work over a pool of one worker per CPU.
+// Every public key must belong to an enabled account, otherwise nothing is signed.
+// If slashing protection is enabled, each account is checked and recorded separately,
+// and the first conflict found fails the whole batch. If an audit log is in use, the
+// request of each account is recorded in it, like a call to Sign.
+func (km *Keymanager) SignBatch(slotInfo *SlotInfo, domain Domain, pubKeys [][48]byte) (*BatchSignature, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no public keys to sign with")
+	}
+	secretKeys := make([]bls.SecretKey, len(pubKeys))
+	km.lock.RLock()
+	for i, pubKey := range pubKeys {
+		secretKey, ok := km.secretKeysCache[pubKey]
+		if !ok {
+			km.lock.RUnlock()
+			return nil, errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+		}
+		if km.disabledPublicKeys[pubKey] {
+			km.lock.RUnlock()
+			return nil, errors.Wrapf(ErrDisabledPublicKey, "%#x", pubKey)
+		}
+		secretKeys[i] = secretKey
+	}
+	protection := km.slashingProtection
+	auditLog := km.auditLog
+	encoding := km.encoding
+	km.lock.RUnlock()
+	signingRoot, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := make([]bls.Signature, len(pubKeys))
+	errs := make([]error, len(pubKeys))
+	indices := make(chan int)
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(pubKeys) {
+		numWorkers = len(pubKeys)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				err := checkSlashingProtection(protection, slotInfo.Slot, signingRoot, pubKeys[i])
+				if err == nil {
+					signatures[i] = secretKeys[i].Sign(signingRoot[:])
+				}
+				if auditErr := recordSigning(auditLog, pubKeys[i], slotInfo, signingRoot[:], signatures[i], err); auditErr != nil {
+					err = auditErr
+				}
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "could not sign with %#x", pubKeys[i])
+				}
+			}
+		}()
+	}
+	for i := range pubKeys {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([][48]byte, len(pubKeys))
+	copy(keys, pubKeys)
+	return &BatchSignature{
+		PublicKeys: keys,
+		Signatures: signatures,
+		Aggregate:  bls.AggregateSignatures(signatures),
+	}, nil
+}
+
+// VerifyBatch verifies an aggregate signature over the slot info in the domain by the
+// accounts of the given public keys, as returned by SignBatch.
+func (km *Keymanager) VerifyBatch(slotInfo *SlotInfo, domain Domain, pubKeys [][48]byte, aggregate bls.Signature) error {
+	if len(pubKeys) == 0 {
+		return errors.New("no public keys to verify with")
+	}
+	publicKeys := make([]bls.PublicKey, len(pubKeys))
+	km.lock.RLock()
+	for i, pubKey := range pubKeys {
+		secretKey, ok := km.secretKeysCache[pubKey]
+		if !ok {
+			km.lock.RUnlock()
+			return errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+		}
+		publicKeys[i] = secretKey.PublicKey()
+	}
+	encoding := km.encoding
+	km.lock.RUnlock()
+	signingRoot, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+	if err != nil {
+		return err
+	}
+	if !aggregate.FastAggregateVerify(publicKeys, signingRoot) {
+		return ErrSigFailedToVerify
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+)
+
+// setupBatchKeymanager adds random accounts to the keymanager of a new wallet, in
+// memory only, to avoid encrypting a keystore per account.
+func setupBatchKeymanager(tb testing.TB, numAccounts int) (*Keymanager, [][48]byte) {
+	km, err := NewImportedKeymanager(context.Background(), setupImportedWallet(tb))
+	require.NoError(tb, err)
+	privKeys := make([][]byte, numAccounts)
+	pubKeys := make([][]byte, numAccounts)
+	validatingPubKeys := make([][48]byte, numAccounts)
+	for i := 0; i < numAccounts; i++ {
+		secretKey, err := bls.RandKey()
+		require.NoError(tb, err)
+		privKeys[i] = secretKey.Marshal()
+		pubKeys[i] = secretKey.PublicKey().Marshal()
+		validatingPubKeys[i] = bytesutil.ToBytes48(pubKeys[i])
+	}
+	km.lock.Lock()
+	km.addAccounts(privKeys, pubKeys)
+	err = km.initializeKeysCachesFromKeystore()
+	km.lock.Unlock()
+	require.NoError(tb, err)
+	return km, validatingPubKeys
+}
+
+func TestKeymanager_SignBatch(t *testing.T) {
+	km, pubKeys := setupBatchKeymanager(t, 16)
+	slotInfo := NewSlotInfo(2, 64, 5454)
+
+	batch, err := km.SignBatch(slotInfo, testDomain, pubKeys)
+	require.NoError(t, err)
+	require.DeepEqual(t, pubKeys, batch.PublicKeys)
+	require.Equal(t, len(pubKeys), len(batch.Signatures))
+	for i, signature := range batch.Signatures {
+		expected, err := km.Sign(slotInfo, testDomain, pubKeys[i])
+		require.NoError(t, err)
+		require.DeepEqual(t, expected.Marshal(), signature.Marshal())
+	}
+	require.DeepEqual(t, bls.AggregateSignatures(batch.Signatures).Marshal(), batch.Aggregate.Marshal())
+
+	require.NoError(t, km.VerifyBatch(slotInfo, testDomain, pubKeys, batch.Aggregate))
+	require.Equal(t, ErrSigFailedToVerify, km.VerifyBatch(slotInfo, testDomain, pubKeys[1:], batch.Aggregate))
+	require.Equal(t, ErrSigFailedToVerify, km.VerifyBatch(NewSlotInfo(2, 65, 5454), testDomain, pubKeys, batch.Aggregate))
+
+	_, err = km.SignBatch(slotInfo, testDomain, append(pubKeys, [48]byte{1, 2, 3}))
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	km.disabledPublicKeys[pubKeys[3]] = true
+	_, err = km.SignBatch(slotInfo, testDomain, pubKeys)
+	require.Equal(t, true, errors.Is(err, ErrDisabledPublicKey))
+	_, err = km.SignBatch(slotInfo, testDomain, nil)
+	require.ErrorContains(t, "no public keys", err)
+}
+
+func TestKeymanager_SignBatch_SlashingProtection(t *testing.T) {
+	km, pubKeys := setupBatchKeymanager(t, 4)
+	store, err := slashingprotection.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+	km.UseSlashingProtection(store)
+
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKeys[2])
+	require.NoError(t, err)
+	_, err = km.SignBatch(NewSlotInfo(2, 64, 5454), testDomain, pubKeys)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+	_, err = km.SignBatch(NewSlotInfo(2, 65, 5454), testDomain, pubKeys)
+	require.NoError(t, err)
+}
+
+func BenchmarkKeymanager_SignBatch(b *testing.B) {
+	km, pubKeys := setupBatchKeymanager(b, 64)
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := km.SignBatch(slotInfo, testDomain, pubKeys); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkKeymanager_SignLoop(b *testing.B) {
+	km, pubKeys := setupBatchKeymanager(b, 64)
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, pubKey := range pubKeys {
+			if _, err := km.Sign(slotInfo, testDomain, pubKey); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/pkg/errors"
+)
+
+// CheckError is a problem found in a wallet directory by Check. It wraps one of the
+// sentinel errors of this package, so problems can be told apart with errors.Is.
+type CheckError struct {
+	// Path of the file or directory the problem was found at.
+	Path string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// CheckReport lists the problems found in a wallet directory by Check.
+type CheckReport struct {
+	WalletDir string
+	// KeymanagerKind of the wallet, only meaningful when a single keymanager folder was found.
+	KeymanagerKind Kind
+	// NumAccounts stored in the keystores of the wallet which could be decrypted.
+	NumAccounts int
+	Problems    []*CheckError
+}
+
+// Valid returns true if no problem was found in the wallet directory.
+func (r *CheckReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *CheckReport) addProblem(path string, err error) {
+	r.Problems = append(r.Problems, &CheckError{Path: path, Err: err})
+}
+
+// Check inspects the wallet directory and lists every problem found in it: missing or
+// multiple keymanager folders, files accessible by other users, unreadable or corrupt
+// keystores, and accounts whose private key does not derive their stated public key.
+// Keystores are decrypted with the wallet password. Unlike OpenWallet, Check does not
+// stop at the first problem, and works on wallets which could not be opened.
+func (w *Wallet) Check() *CheckReport {
+	report := &CheckReport{WalletDir: w.walletDir}
+	info, err := os.Stat(w.walletDir)
+	if os.IsNotExist(err) {
+		report.addProblem(w.walletDir, ErrNoWalletFound)
+		return report
+	} else if err != nil {
+		report.addProblem(w.walletDir, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+		return report
+	}
+	if !info.IsDir() {
+		report.addProblem(w.walletDir, ErrNoWalletFound)
+		return report
+	}
+	checkPermissions(report, w.walletDir)
+
+	kinds, err := keymanagerKindsAtWalletPath(w.walletDir)
+	if err != nil {
+		report.addProblem(w.walletDir, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+		return report
+	}
+	switch len(kinds) {
+	case 0:
+		report.addProblem(w.walletDir, ErrNoKeymanagerFolder)
+		return report
+	case 1:
+		report.KeymanagerKind = kinds[0]
+	default:
+		names := make([]string, len(kinds))
+		for i, kind := range kinds {
+			names[i] = kind.String()
+		}
+		report.addProblem(w.walletDir, errors.Wrapf(ErrMultipleKeymanagers, "found %s", strings.Join(names, ", ")))
+		return report
+	}
+
+	accountsPath := filepath.Join(w.walletDir, report.KeymanagerKind.String())
+	configFilePath := filepath.Join(accountsPath, KeymanagerConfigFileName)
+	switch report.KeymanagerKind {
+	case Imported:
+		checkKeymanagerConfig(report, configFilePath, &KeymanagerOpts{}, false)
+		w.checkAccountsKeystore(report, filepath.Join(accountsPath, AccountsPath, AccountsKeystoreFileName))
+	case Derived:
+		checkKeymanagerConfig(report, configFilePath, &DerivedKeymanagerOpts{}, false)
+		w.checkSeedFile(report, filepath.Join(accountsPath, EncryptedSeedFileName))
+	case Remote:
+		checkKeymanagerConfig(report, configFilePath, &RemoteKeymanagerOpts{}, true)
+	}
+	return report
+}
+
+// checkAccountsKeystore decrypts the accounts keystore of an imported wallet and checks
+// every account in it. A wallet without accounts keystore holds no accounts yet.
+func (w *Wallet) checkAccountsKeystore(report *CheckReport, path string) {
+	encoded, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+		return
+	}
+	keystoreFile := &AccountsKeystoreRepresentation{}
+	if err := json.Unmarshal(encoded, keystoreFile); err != nil {
+		report.addProblem(path, errors.Wrap(ErrCorruptKeystore, err.Error()))
+		return
+	}
+	for _, pubKey := range keystoreFile.DisabledPublicKeys {
+		if pubKeyBytes, err := hex.DecodeString(pubKey); err != nil || len(pubKeyBytes) != 48 {
+			report.addProblem(path, errors.Wrapf(ErrCorruptKeystore, "invalid disabled public key %s", pubKey))
+		}
+	}
+	decrypted, ok := w.decryptCheckedKeystore(report, path, keystoreFile.Crypto)
+	if !ok {
+		return
+	}
+	store := &accountStore{}
+	if err := json.Unmarshal(decrypted, store); err != nil {
+		report.addProblem(path, errors.Wrap(ErrCorruptKeystore, err.Error()))
+		return
+	}
+	if len(store.PrivateKeys) != len(store.PublicKeys) {
+		report.addProblem(path, errors.Wrapf(
+			ErrKeyCountMismatch, "%d private keys, %d public keys", len(store.PrivateKeys), len(store.PublicKeys),
+		))
+		return
+	}
+	for i, privKeyBytes := range store.PrivateKeys {
+		privKey, err := bls.SecretKeyFromBytes(privKeyBytes)
+		if err != nil {
+			report.addProblem(path, errors.Wrapf(ErrCorruptKeystore, "account %d: %v", i, err))
+			continue
+		}
+		if !bytes.Equal(privKey.PublicKey().Marshal(), store.PublicKeys[i]) {
+			report.addProblem(path, errors.Wrapf(ErrPublicKeyMismatch, "account %d with public key %#x", i, store.PublicKeys[i]))
+		}
+	}
+	report.NumAccounts = len(store.PublicKeys)
+}
+
+// checkSeedFile decrypts the seed of a derived wallet.
+func (w *Wallet) checkSeedFile(report *CheckReport, path string) {
+	encoded, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, "seed file is missing"))
+		return
+	} else if err != nil {
+		report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+		return
+	}
+	seedCfg := &SeedConfig{}
+	if err := json.Unmarshal(encoded, seedCfg); err != nil {
+		report.addProblem(path, errors.Wrap(ErrCorruptKeystore, err.Error()))
+		return
+	}
+	if _, ok := w.decryptCheckedKeystore(report, path, seedCfg.Crypto); ok {
+		report.NumAccounts = int(seedCfg.NextAccount)
+	}
+}
+
+// decryptCheckedKeystore decrypts the crypto fields of a keystore with the wallet
+// password, reporting a problem if they cannot be.
+func (w *Wallet) decryptCheckedKeystore(report *CheckReport, path string, cryptoFields map[string]interface{}) ([]byte, bool) {
+	decrypted, err := decryptKeystoreCrypto(cryptoFields, w.walletPassword)
+	if errors.Is(err, ErrWrongPassword) {
+		report.addProblem(path, ErrWrongPassword)
+		return nil, false
+	} else if err != nil {
+		report.addProblem(path, errors.Wrap(ErrCorruptKeystore, err.Error()))
+		return nil, false
+	}
+	return decrypted, true
+}
+
+// checkKeymanagerConfig decodes the keymanager options file into opts. Only remote
+// wallets require the file, the others fall back to their defaults without it.
+func checkKeymanagerConfig(report *CheckReport, path string, opts interface{}, required bool) {
+	encoded, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if required {
+			report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, "keymanager config file is missing"))
+		}
+		return
+	} else if err != nil {
+		report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+		return
+	}
+	if err := json.Unmarshal(encoded, opts); err != nil {
+		report.addProblem(path, errors.Wrap(ErrCorruptKeystore, err.Error()))
+	}
+}
+
+// checkPermissions reports every file and directory under the wallet directory which
+// is accessible by other users. Windows does not expose permissions as mode bits, so
+// nothing is checked there.
+func checkPermissions(report *CheckReport, walletDir string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	err := filepath.Walk(walletDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			report.addProblem(path, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			report.addProblem(path, errors.Wrapf(ErrInsecurePermissions, "found %#o", info.Mode().Perm()))
+		}
+		return nil
+	})
+	if err != nil {
+		report.addProblem(walletDir, errors.Wrap(ErrUnreadableKeystore, err.Error()))
+	}
+}
+
+// keymanagerKindsAtWalletPath lists the kinds of the keymanager folders in a wallet directory.
+func keymanagerKindsAtWalletPath(walletDir string) ([]Kind, error) {
+	infos, err := ioutil.ReadDir(walletDir)
+	if err != nil {
+		return nil, err
+	}
+	var kinds []Kind
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		if kind, err := ParseKind(info.Name()); err == nil {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+func hasProblem(report *CheckReport, target error) bool {
+	for _, problem := range report.Problems {
+		if errors.Is(problem, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAccountsStore encrypts an account store as is, without the consistency checks
+// of newAccountsKeystore, and writes it as the accounts keystore of the wallet.
+func writeAccountsStore(t *testing.T, w *Wallet, store *accountStore) string {
+	encodedStore, err := json.Marshal(store)
+	require.NoError(t, err)
+	cryptoFields, err := keystorev4.New().Encrypt(encodedStore, testWalletPassword)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(&AccountsKeystoreRepresentation{Crypto: cryptoFields})
+	require.NoError(t, err)
+	path := filepath.Join(w.accountsPath, AccountsPath, AccountsKeystoreFileName)
+	require.NoError(t, ioutil.WriteFile(path, encoded, 0600))
+	return path
+}
+
+func TestWallet_Check(t *testing.T) {
+	w := setupImportedWallet(t)
+	_, secretKey := createRandomKeystore(t, "password")
+	writeAccountsStore(t, w, &accountStore{
+		PrivateKeys: [][]byte{secretKey.Marshal()},
+		PublicKeys:  [][]byte{secretKey.PublicKey().Marshal()},
+	})
+
+	report := w.Check()
+	require.Equal(t, true, report.Valid(), "Unexpected problems: %v", report.Problems)
+	require.Equal(t, Imported, report.KeymanagerKind)
+	require.Equal(t, 1, report.NumAccounts)
+
+	report = NewWallet(&Config{WalletDir: w.walletDir, WalletPassword: "wrong password"}).Check()
+	require.Equal(t, 1, len(report.Problems))
+	require.Equal(t, true, errors.Is(report.Problems[0], ErrWrongPassword))
+}
+
+func TestWallet_Check_Derived(t *testing.T) {
+	w := setupDerivedWallet(t)
+	report := w.Check()
+	require.Equal(t, true, hasProblem(report, ErrUnreadableKeystore), "Expected the missing seed to be reported")
+
+	_, err := RecoverFromMnemonic(context.Background(), w, testMnemonic, "", 3, false /* overwrite */)
+	require.NoError(t, err)
+	report = w.Check()
+	require.Equal(t, true, report.Valid(), "Unexpected problems: %v", report.Problems)
+	require.Equal(t, Derived, report.KeymanagerKind)
+	require.Equal(t, 3, report.NumAccounts)
+}
+
+func TestWallet_Check_Problems(t *testing.T) {
+	report := NewWallet(&Config{WalletDir: filepath.Join(t.TempDir(), "missing")}).Check()
+	require.Equal(t, true, hasProblem(report, ErrNoWalletFound))
+
+	w := setupImportedWallet(t)
+	require.NoError(t, os.Mkdir(filepath.Join(w.walletDir, Derived.String()), 0700))
+	report = w.Check()
+	require.Equal(t, true, hasProblem(report, ErrMultipleKeymanagers))
+
+	w = setupImportedWallet(t)
+	_, secretKey1 := createRandomKeystore(t, "password")
+	_, secretKey2 := createRandomKeystore(t, "password")
+	path := writeAccountsStore(t, w, &accountStore{
+		PrivateKeys: [][]byte{secretKey1.Marshal(), secretKey2.Marshal()},
+		PublicKeys:  [][]byte{secretKey1.PublicKey().Marshal()},
+	})
+	report = w.Check()
+	require.Equal(t, true, hasProblem(report, ErrKeyCountMismatch))
+
+	writeAccountsStore(t, w, &accountStore{
+		PrivateKeys: [][]byte{secretKey1.Marshal(), secretKey2.Marshal()},
+		PublicKeys:  [][]byte{secretKey1.PublicKey().Marshal(), secretKey1.PublicKey().Marshal()},
+	})
+	report = w.Check()
+	require.Equal(t, 1, len(report.Problems))
+	require.Equal(t, true, errors.Is(report.Problems[0], ErrPublicKeyMismatch))
+	require.Equal(t, path, report.Problems[0].Path)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("{not json"), 0600))
+	require.NoError(t, os.Chmod(path, 0644))
+	report = w.Check()
+	require.Equal(t, true, hasProblem(report, ErrCorruptKeystore))
+	require.Equal(t, true, hasProblem(report, ErrInsecurePermissions))
+}
+
+func TestWallet_Check_Fixture(t *testing.T) {
+	report := NewWallet(&Config{WalletDir: "./prysm-wallet-v2", WalletPassword: "Konasl@123"}).Check()
+	require.Equal(t, Imported, report.KeymanagerKind)
+	require.Equal(t, 2, report.NumAccounts)
+	for _, problem := range report.Problems {
+		require.Equal(t, true, errors.Is(problem, ErrInsecurePermissions), "Unexpected problem: %v", problem)
+	}
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/pkg/errors"
+)
+
+// DeleteAccounts removes the accounts of the given public keys from the keymanager and
+// rewrites the accounts keystore without them. The removed accounts are returned as
+// EIP-2335 keystores encrypted with the wallet password, so they can be archived.
+// Their slashing protection history, if any, is kept.
+func (km *Keymanager) DeleteAccounts(ctx context.Context, pubKeys [][48]byte) ([]*Keystore, error) {
+	km.writeLock.Lock()
+	km.lock.Lock()
+	removed, err := km.exportKeystores(pubKeys, km.wallet.walletPassword, true /* includeDisabled */)
+	if err != nil {
+		km.lock.Unlock()
+		km.writeLock.Unlock()
+		return nil, errors.Wrap(err, "could not export accounts to delete")
+	}
+	toDelete := make(map[[48]byte]bool, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		toDelete[pubKey] = true
+	}
+	previousStore, previousDisabled := km.accountsStore, km.disabledPublicKeys
+	store := &accountStore{
+		PrivateKeys:   make([][]byte, 0, len(previousStore.PrivateKeys)),
+		PublicKeys:    make([][]byte, 0, len(previousStore.PublicKeys)),
+		CreationTimes: make([]int64, 0, len(previousStore.PublicKeys)),
+	}
+	for i, pubKey := range previousStore.PublicKeys {
+		if toDelete[bytesutil.ToBytes48(pubKey)] {
+			continue
+		}
+		store.PrivateKeys = append(store.PrivateKeys, previousStore.PrivateKeys[i])
+		store.PublicKeys = append(store.PublicKeys, pubKey)
+		if i < len(previousStore.CreationTimes) {
+			store.CreationTimes = append(store.CreationTimes, previousStore.CreationTimes[i])
+		} else {
+			store.CreationTimes = append(store.CreationTimes, 0)
+		}
+	}
+	disabledPublicKeys := make(map[[48]byte]bool, len(previousDisabled))
+	for pubKey := range previousDisabled {
+		if !toDelete[pubKey] {
+			disabledPublicKeys[pubKey] = true
+		}
+	}
+	km.accountsStore = store
+	km.disabledPublicKeys = disabledPublicKeys
+	err = km.initializeKeysCachesFromKeystore()
+	km.lock.Unlock()
+	if err == nil {
+		err = km.writeAccountsKeystore(ctx)
+	}
+	if err != nil {
+		km.lock.Lock()
+		km.accountsStore = previousStore
+		km.disabledPublicKeys = previousDisabled
+		if cacheErr := km.initializeKeysCachesFromKeystore(); cacheErr != nil {
+			log.WithError(cacheErr).Error("Could not restore keys caches")
+		}
+		km.lock.Unlock()
+		km.writeLock.Unlock()
+		return nil, errors.Wrap(err, "could not delete accounts")
+	}
+	km.writeLock.Unlock()
+
+	log.WithField("numAccounts", len(removed)).Info("Deleted accounts from wallet")
+	validatingPubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	km.accountsChangedFeed.Send(validatingPubKeys)
+	return removed, nil
+}
@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+func TestKeymanager_DeleteAccounts(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore1, secretKey1 := createRandomKeystore(t, "password")
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	keystore3, secretKey3 := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(
+		ctx, []*Keystore{keystore1, keystore2, keystore3}, []string{"password", "password", "password"},
+	))
+	pubKey1 := bytesutil.ToBytes48(secretKey1.PublicKey().Marshal())
+	pubKey2 := bytesutil.ToBytes48(secretKey2.PublicKey().Marshal())
+	pubKey3 := bytesutil.ToBytes48(secretKey3.PublicKey().Marshal())
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{pubKey2}))
+
+	pubKeysChan := make(chan [][48]byte, 1)
+	sub := km.SubscribeAccountChanges(pubKeysChan)
+	defer sub.Unsubscribe()
+
+	removed, err := km.DeleteAccounts(ctx, [][48]byte{pubKey2, pubKey1})
+	require.NoError(t, err)
+	require.DeepEqual(t, [][48]byte{pubKey3}, <-pubKeysChan)
+
+	// The removed keystores decrypt with the wallet password.
+	require.Equal(t, 2, len(removed))
+	decryptor := keystorev4.New()
+	privKey, err := decryptor.Decrypt(removed[0].Crypto, testWalletPassword)
+	require.NoError(t, err)
+	require.DeepEqual(t, secretKey2.Marshal(), privKey)
+	privKey, err = decryptor.Decrypt(removed[1].Crypto, testWalletPassword)
+	require.NoError(t, err)
+	require.DeepEqual(t, secretKey1.Marshal(), privKey)
+
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey1)
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	require.Equal(t, 0, len(km.disabledPublicKeys))
+
+	reloaded, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.DeepEqual(t, [][48]byte{pubKey3}, reloaded.orderedPublicKeys)
+	require.Equal(t, 0, len(reloaded.disabledPublicKeys))
+}
+
+func TestKeymanager_DeleteAccounts_UnknownKey(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	_, err = km.DeleteAccounts(ctx, [][48]byte{pubKey, {1, 2, 3}})
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	// Nothing is deleted when one of the keys is unknown.
+	require.DeepEqual(t, [][48]byte{pubKey}, km.orderedPublicKeys)
+}
@@ -0,0 +1,358 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// DerivedEIPNumber used by the derived keymanager implementation.
+	DerivedEIPNumber = "EIP-2334"
+	// ValidatingKeyDerivationPathTemplate defining the hierarchical path for validating
+	// keys. According to EIP-2334, the format is as follows:
+	// m / purpose / coin_type / account_index / withdrawal_key / validating_key
+	ValidatingKeyDerivationPathTemplate = "m/12381/3600/%d/0/0"
+	// EncryptedSeedFileName for persisting a wallet's seed when using a derived keymanager.
+	EncryptedSeedFileName = "seed.encrypted.json"
+	// Entropy used to generate new mnemonics, yielding 24 words.
+	mnemonicEntropySize = 256
+)
+
+// DerivedKeymanagerOpts for a derived keymanager.
+type DerivedKeymanagerOpts struct {
+	DerivedPathStructure string     `json:"derived_path_structure"`
+	DerivedEIPNumber     string     `json:"derived_eip_number"`
+	KDFParams            *KDFParams `json:"kdf_params,omitempty"`
+}
+
+// DefaultDerivedKeymanagerOpts for a derived keymanager.
+func DefaultDerivedKeymanagerOpts() *DerivedKeymanagerOpts {
+	return &DerivedKeymanagerOpts{
+		DerivedPathStructure: "m / purpose / coin_type / account_index / withdrawal_key / validating_key",
+		DerivedEIPNumber:     DerivedEIPNumber,
+	}
+}
+
+// SeedConfig json file representation as a Go struct. Only the encrypted seed
+// and the index of the next account to derive are persisted, every key is
+// derived from the seed when the keymanager is opened.
+type SeedConfig struct {
+	Crypto      map[string]interface{} `json:"crypto"`
+	ID          string                 `json:"uuid"`
+	NextAccount uint64                 `json:"next_account"`
+	Version     uint                   `json:"version"`
+	Name        string                 `json:"name"`
+}
+
+// DerivedKeymanager implementation for hierarchical-deterministic keys
+// utilizing EIP-2333 and EIP-2334.
+type DerivedKeymanager struct {
+	wallet              *Wallet
+	seed                []byte
+	seedCfg             *SeedConfig
+	lock                sync.RWMutex
+	orderedPublicKeys   [][48]byte
+	secretKeysCache     map[[48]byte]bls.SecretKey
+	accountsChangedFeed *event.Feed
+	slashingProtection  *slashingprotection.Store
+	encoding            Encoding
+	// seedPassword is the wallet password seedCfg is encrypted with.
+	seedPassword string
+}
+
+// GenerateMnemonic creates a new, random BIP-39 mnemonic of 24 words.
+func GenerateMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropySize)
+	if err != nil {
+		return "", errors.Wrap(err, "could not generate entropy")
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewDerivedKeymanager opens the derived keymanager of a wallet, decrypting its
+// seed with the wallet password and deriving every account created so far. It checks
+// signatures against the slashing protection database of the wallet.
+func NewDerivedKeymanager(ctx context.Context, wallet *Wallet) (*DerivedKeymanager, error) {
+	wallet.passwordLock.RLock()
+	defer wallet.passwordLock.RUnlock()
+	encoded, err := wallet.ReadFileAtPath("", EncryptedSeedFileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read seed file %s", EncryptedSeedFileName)
+	}
+	seedCfg := &SeedConfig{}
+	if err := json.Unmarshal(encoded, seedCfg); err != nil {
+		return nil, errors.Wrapf(err, "could not decode seed file %s", EncryptedSeedFileName)
+	}
+	wallet.warnIfWeakerKDF(EncryptedSeedFileName, seedCfg.Crypto)
+	seed, err := decryptKeystoreCrypto(seedCfg.Crypto, wallet.walletPassword)
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not decrypt seed")
+	}
+	store, err := wallet.SlashingProtection()
+	if err != nil {
+		return nil, err
+	}
+	km := &DerivedKeymanager{
+		wallet:              wallet,
+		seed:                seed,
+		seedCfg:             seedCfg,
+		seedPassword:        wallet.walletPassword,
+		accountsChangedFeed: new(event.Feed),
+		slashingProtection:  store,
+	}
+	if err := km.initializeKeysCachesFromSeed(); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize keys caches")
+	}
+	return km, nil
+}
+
+// RecoverFromMnemonic writes the seed of a BIP-39 mnemonic, encrypted with the
+// wallet password, into a derived wallet and opens its keymanager with the first
+// numAccounts accounts. Returns ErrSeedExists if the wallet already holds a seed,
+// unless overwrite is set, which replaces that seed and loses its accounts.
+func RecoverFromMnemonic(
+	ctx context.Context,
+	wallet *Wallet,
+	mnemonic, mnemonicPassphrase string,
+	numAccounts uint64,
+	overwrite bool,
+) (*DerivedKeymanager, error) {
+	if wallet.keymanagerKind != Derived {
+		return nil, fmt.Errorf("cannot recover a mnemonic into a %s wallet", wallet.keymanagerKind)
+	}
+	if !overwrite && fileutil.FileExists(filepath.Join(wallet.accountsPath, EncryptedSeedFileName)) {
+		return nil, errors.Wrapf(ErrSeedExists, "found %s", EncryptedSeedFileName)
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, mnemonicPassphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not generate seed from mnemonic")
+	}
+	store, err := wallet.SlashingProtection()
+	if err != nil {
+		return nil, err
+	}
+	km := &DerivedKeymanager{
+		wallet:              wallet,
+		seed:                seed,
+		accountsChangedFeed: new(event.Feed),
+		slashingProtection:  store,
+	}
+	wallet.passwordLock.RLock()
+	seedCfg, err := km.newSeedConfig(numAccounts)
+	if err == nil {
+		err = km.writeSeedConfig(ctx, seedCfg)
+	}
+	wallet.passwordLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := km.initializeKeysCachesFromSeed(); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize keys caches")
+	}
+	return km, nil
+}
+
+// CreateAccount derives the next validating key from the seed, persists the
+// incremented account index and returns the new account's public key. The seed is
+// re-encrypted if the wallet password changed since the keymanager was opened.
+func (km *DerivedKeymanager) CreateAccount(ctx context.Context) ([]byte, error) {
+	km.lock.Lock()
+	index := km.seedCfg.NextAccount
+	secretKey, err := km.deriveValidatingKey(index)
+	if err != nil {
+		km.lock.Unlock()
+		return nil, err
+	}
+	km.wallet.passwordLock.RLock()
+	err = km.writeNextAccount(ctx, index+1)
+	km.wallet.passwordLock.RUnlock()
+	if err != nil {
+		km.lock.Unlock()
+		return nil, err
+	}
+	publicKey := secretKey.PublicKey().Marshal()
+	publicKey48 := bytesutil.ToBytes48(publicKey)
+	km.orderedPublicKeys = append(km.orderedPublicKeys, publicKey48)
+	km.secretKeysCache[publicKey48] = secretKey
+	pubKeys := make([][48]byte, len(km.orderedPublicKeys))
+	copy(pubKeys, km.orderedPublicKeys)
+	km.lock.Unlock()
+
+	log.WithField(
+		"path", fmt.Sprintf(ValidatingKeyDerivationPathTemplate, index),
+	).Info("Created new derived account")
+	km.accountsChangedFeed.Send(pubKeys)
+	return publicKey, nil
+}
+
+// FetchValidatingPublicKeys fetches the list of public keys derived so far, in account order.
+func (km *DerivedKeymanager) FetchValidatingPublicKeys(_ context.Context) ([][48]byte, error) {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	keys := make([][48]byte, len(km.orderedPublicKeys))
+	copy(keys, km.orderedPublicKeys)
+	return keys, nil
+}
+
+// SubscribeAccountChanges creates an event subscription for a channel
+// to listen for public key changes at runtime, such as when new accounts are created.
+func (km *DerivedKeymanager) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	return km.accountsChangedFeed.Subscribe(pubKeysChan)
+}
+
+// UseSlashingProtection makes the keymanager record every signed slot in the given
+// store, instead of the one of the wallet, and refuse to sign a conflicting slot info
+// for the same slot. A nil store disables slashing protection.
+func (km *DerivedKeymanager) UseSlashingProtection(store *slashingprotection.Store) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.slashingProtection = store
+}
+
+// SetEncoding selects the encoding of slot infos from which signing roots are
+// computed. RLPEncoding is used by default.
+func (km *DerivedKeymanager) SetEncoding(encoding Encoding) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.encoding = encoding
+}
+
+// Sign signs the signing root of the slot info in the domain using the validating key
+// of the given public key.
+func (km *DerivedKeymanager) Sign(slotInfo *SlotInfo, domain Domain, pubKey [48]byte) (bls.Signature, error) {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeysCache[pubKey]
+	protection := km.slashingProtection
+	encoding := km.encoding
+	km.lock.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	signingRoot, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSlashingProtection(protection, slotInfo.Slot, signingRoot, pubKey); err != nil {
+		return nil, err
+	}
+	return secretKey.Sign(signingRoot[:]), nil
+}
+
+// VerifySignature verifies a signature over the slot info in the domain given the
+// public key of one of the derived accounts.
+func (km *DerivedKeymanager) VerifySignature(slotInfo *SlotInfo, domain Domain, pubKey [48]byte, signature bls.Signature) error {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeysCache[pubKey]
+	encoding := km.encoding
+	km.lock.RUnlock()
+	if !ok {
+		return errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	signingRoot, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(secretKey.PublicKey(), signingRoot[:]) {
+		return ErrSigFailedToVerify
+	}
+	return nil
+}
+
+// Derives the validating keys of every account created so far.
+func (km *DerivedKeymanager) initializeKeysCachesFromSeed() error {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	count := km.seedCfg.NextAccount
+	km.orderedPublicKeys = make([][48]byte, count)
+	km.secretKeysCache = make(map[[48]byte]bls.SecretKey, count)
+	for i := uint64(0); i < count; i++ {
+		secretKey, err := km.deriveValidatingKey(i)
+		if err != nil {
+			return err
+		}
+		publicKey48 := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+		km.orderedPublicKeys[i] = publicKey48
+		km.secretKeysCache[publicKey48] = secretKey
+	}
+	return nil
+}
+
+func (km *DerivedKeymanager) deriveValidatingKey(index uint64) (bls.SecretKey, error) {
+	path := fmt.Sprintf(ValidatingKeyDerivationPathTemplate, index)
+	privKey, err := PrivateKeyFromSeedAndPath(km.seed, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not derive validating key at path %s", path)
+	}
+	secretKey, err := bls.SecretKeyFromBytes(privKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not initialize validating key at path %s", path)
+	}
+	return secretKey, nil
+}
+
+// newSeedConfig encrypts the keymanager's seed with the wallet password.
+func (km *DerivedKeymanager) newSeedConfig(nextAccount uint64) (*SeedConfig, error) {
+	encryptor := newKeystoreEncryptor(km.wallet.kdfParams)
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	cryptoFields, err := encryptor.Encrypt(km.seed, km.wallet.walletPassword)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encrypt seed")
+	}
+	return &SeedConfig{
+		Crypto:      cryptoFields,
+		ID:          id.String(),
+		NextAccount: nextAccount,
+		Version:     encryptor.Version(),
+		Name:        encryptor.Name(),
+	}, nil
+}
+
+// writeNextAccount persists the index of the next account to derive. The encrypted seed
+// is kept as is unless it was encrypted with a previous wallet password, in which case
+// writing it back would lock the wallet under that password again. The caller must
+// hold the read lock of the wallet password.
+func (km *DerivedKeymanager) writeNextAccount(ctx context.Context, nextAccount uint64) error {
+	seedCfg := *km.seedCfg
+	seedCfg.NextAccount = nextAccount
+	if km.seedPassword != km.wallet.walletPassword {
+		reencrypted, err := km.newSeedConfig(nextAccount)
+		if err != nil {
+			return err
+		}
+		reencrypted.ID = seedCfg.ID
+		seedCfg = *reencrypted
+	}
+	return km.writeSeedConfig(ctx, &seedCfg)
+}
+
+// writeSeedConfig writes the seed file, which must be encrypted with the current wallet
+// password. The caller must hold the read lock of the wallet password.
+func (km *DerivedKeymanager) writeSeedConfig(ctx context.Context, seedCfg *SeedConfig) error {
+	encoded, err := json.MarshalIndent(seedCfg, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := km.wallet.WriteFileAtPath(ctx, "", EncryptedSeedFileName, encoded); err != nil {
+		return errors.Wrap(err, "could not write seed file")
+	}
+	km.seedCfg = seedCfg
+	km.seedPassword = km.wallet.walletPassword
+	return nil
+}
@@ -0,0 +1,131 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func setupDerivedWallet(t testing.TB) *Wallet {
+	w, err := CreateWallet(context.Background(), &Config{
+		WalletDir:      filepath.Join(t.TempDir(), "wallet"),
+		KeymanagerKind: Derived,
+		WalletPassword: testWalletPassword,
+		KDFParams:      FastKDFParams(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, w.Close())
+	})
+	return w
+}
+
+func TestGenerateMnemonic(t *testing.T) {
+	mnemonic, err := GenerateMnemonic()
+	require.NoError(t, err)
+	require.Equal(t, 24, len(strings.Fields(mnemonic)))
+	require.Equal(t, true, bip39.IsMnemonicValid(mnemonic))
+}
+
+func TestRecoverFromMnemonic(t *testing.T) {
+	ctx := context.Background()
+	w := setupDerivedWallet(t)
+
+	_, err := RecoverFromMnemonic(ctx, w, "invalid mnemonic", "", 1, false /* overwrite */)
+	require.ErrorContains(t, "could not generate seed from mnemonic", err)
+
+	km, err := RecoverFromMnemonic(ctx, w, testMnemonic, "TREZOR", 2, false /* overwrite */)
+	require.NoError(t, err)
+	// The seed of this mnemonic is the first EIP-2333 test vector seed.
+	require.Equal(
+		t,
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		hex.EncodeToString(km.seed),
+	)
+	require.Equal(t, 2, len(km.orderedPublicKeys))
+
+	privKey, err := PrivateKeyFromSeedAndPath(km.seed, "m/12381/3600/1/0/0")
+	require.NoError(t, err)
+	secretKey, err := bls.SecretKeyFromBytes(privKey)
+	require.NoError(t, err)
+	require.DeepEqual(t, secretKey.PublicKey().Marshal(), km.orderedPublicKeys[1][:])
+
+	// Only the encrypted seed is persisted.
+	encoded, err := w.ReadFileAtPath("", EncryptedSeedFileName)
+	require.NoError(t, err)
+	require.Equal(t, false, strings.Contains(string(encoded), hex.EncodeToString(km.seed)))
+}
+
+func TestRecoverFromMnemonic_ExistingSeed(t *testing.T) {
+	ctx := context.Background()
+	w := setupDerivedWallet(t)
+	km, err := RecoverFromMnemonic(ctx, w, testMnemonic, "", 2, false /* overwrite */)
+	require.NoError(t, err)
+	_, err = km.CreateAccount(ctx)
+	require.NoError(t, err)
+	otherMnemonic, err := GenerateMnemonic()
+	require.NoError(t, err)
+
+	// The seed and its accounts survive an attempt to recover another mnemonic.
+	_, err = RecoverFromMnemonic(ctx, w, otherMnemonic, "", 1, false /* overwrite */)
+	require.Equal(t, true, errors.Is(err, ErrSeedExists))
+	reopened, err := NewDerivedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.DeepEqual(t, km.seed, reopened.seed)
+	require.Equal(t, uint64(3), reopened.seedCfg.NextAccount)
+
+	replaced, err := RecoverFromMnemonic(ctx, w, otherMnemonic, "", 1, true /* overwrite */)
+	require.NoError(t, err)
+	reopened, err = NewDerivedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.DeepEqual(t, replaced.seed, reopened.seed)
+	require.Equal(t, uint64(1), reopened.seedCfg.NextAccount)
+}
+
+func TestDerivedKeymanager_CreateAccount(t *testing.T) {
+	ctx := context.Background()
+	w := setupDerivedWallet(t)
+	km, err := RecoverFromMnemonic(ctx, w, testMnemonic, "", 0, false /* overwrite */)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(km.orderedPublicKeys))
+
+	pubKey0, err := km.CreateAccount(ctx)
+	require.NoError(t, err)
+	pubKey1, err := km.CreateAccount(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), km.seedCfg.NextAccount)
+
+	// Reopening the wallet derives the same accounts.
+	reopened, err := NewDerivedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reopened.orderedPublicKeys))
+	require.DeepEqual(t, pubKey0, reopened.orderedPublicKeys[0][:])
+	require.DeepEqual(t, pubKey1, reopened.orderedPublicKeys[1][:])
+
+	w.walletPassword = "wrong"
+	_, err = NewDerivedKeymanager(ctx, w)
+	require.ErrorContains(t, "wrong password for wallet entered", err)
+}
+
+func TestDerivedKeymanager_SignAndVerify(t *testing.T) {
+	km, err := RecoverFromMnemonic(context.Background(), setupDerivedWallet(t), testMnemonic, "", 2, false /* overwrite */)
+	require.NoError(t, err)
+
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	signature, err := km.Sign(slotInfo, testDomain, km.orderedPublicKeys[1])
+	require.NoError(t, err)
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, km.orderedPublicKeys[1], signature))
+	require.ErrorContains(t, ErrSigFailedToVerify.Error(), km.VerifySignature(slotInfo, testDomain, km.orderedPublicKeys[0], signature))
+
+	_, err = km.Sign(slotInfo, testDomain, [48]byte{1})
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+}
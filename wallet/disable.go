@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DisableAccounts marks the accounts of the given public keys as disabled and persists
+// them in the accounts keystore. Disabled accounts can no longer sign and are left
+// out of the validating public keys.
+func (km *Keymanager) DisableAccounts(ctx context.Context, pubKeys [][48]byte) error {
+	return km.setAccountsDisabled(ctx, pubKeys, true)
+}
+
+// EnableAccounts re-enables previously disabled accounts and persists the change in
+// the accounts keystore.
+func (km *Keymanager) EnableAccounts(ctx context.Context, pubKeys [][48]byte) error {
+	return km.setAccountsDisabled(ctx, pubKeys, false)
+}
+
+func (km *Keymanager) setAccountsDisabled(ctx context.Context, pubKeys [][48]byte, disabled bool) error {
+	km.writeLock.Lock()
+	km.lock.Lock()
+	for _, pubKey := range pubKeys {
+		if _, ok := km.secretKeysCache[pubKey]; !ok {
+			km.lock.Unlock()
+			km.writeLock.Unlock()
+			return errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+		}
+	}
+	previous := km.disabledPublicKeys
+	updated := make(map[[48]byte]bool, len(previous)+len(pubKeys))
+	for pubKey := range previous {
+		updated[pubKey] = true
+	}
+	for _, pubKey := range pubKeys {
+		if disabled {
+			updated[pubKey] = true
+		} else {
+			delete(updated, pubKey)
+		}
+	}
+	km.disabledPublicKeys = updated
+	km.lock.Unlock()
+
+	if err := km.writeAccountsKeystore(ctx); err != nil {
+		km.lock.Lock()
+		km.disabledPublicKeys = previous
+		km.lock.Unlock()
+		km.writeLock.Unlock()
+		return err
+	}
+	km.writeLock.Unlock()
+	log.WithFields(logrus.Fields{
+		"numAccounts": len(pubKeys),
+		"disabled":    disabled,
+	}).Info("Updated status of accounts")
+	validatingPubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	if err != nil {
+		return err
+	}
+	km.accountsChangedFeed.Send(validatingPubKeys)
+	return nil
+}
@@ -0,0 +1,79 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func TestKeymanager_DisableEnableAccounts(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore1, secretKey1 := createRandomKeystore(t, "password")
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1, keystore2}, []string{"password", "password"}))
+	pubKey1 := bytesutil.ToBytes48(secretKey1.PublicKey().Marshal())
+	pubKey2 := bytesutil.ToBytes48(secretKey2.PublicKey().Marshal())
+	slotInfo := NewSlotInfo(2, 64, 5454)
+
+	pubKeysChan := make(chan [][48]byte, 2)
+	sub := km.SubscribeAccountChanges(pubKeysChan)
+	defer sub.Unsubscribe()
+
+	// Enabled -> disabled.
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{pubKey1}))
+	require.DeepEqual(t, [][48]byte{pubKey2}, <-pubKeysChan)
+	validating, err := km.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, [][48]byte{pubKey2}, validating)
+	_, err = km.Sign(slotInfo, testDomain, pubKey1)
+	require.Equal(t, true, errors.Is(err, ErrDisabledPublicKey))
+	_, err = km.Sign(slotInfo, testDomain, pubKey2)
+	require.NoError(t, err)
+
+	// The disabled status is persisted in the accounts keystore.
+	encoded, err := w.ReadFileAtPath(AccountsPath, AccountsKeystoreFileName)
+	require.NoError(t, err)
+	keystoreFile := &AccountsKeystoreRepresentation{}
+	require.NoError(t, json.Unmarshal(encoded, keystoreFile))
+	require.DeepEqual(t, []string{fmt.Sprintf("%x", pubKey1)}, keystoreFile.DisabledPublicKeys)
+	reloaded, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	_, err = reloaded.Sign(slotInfo, testDomain, pubKey1)
+	require.Equal(t, true, errors.Is(err, ErrDisabledPublicKey))
+
+	// Disabled -> disabled is a no-op.
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{pubKey1}))
+	require.DeepEqual(t, [][48]byte{pubKey2}, <-pubKeysChan)
+
+	// Disabled -> enabled.
+	require.NoError(t, km.EnableAccounts(ctx, [][48]byte{pubKey1}))
+	require.DeepEqual(t, [][48]byte{pubKey1, pubKey2}, <-pubKeysChan)
+	_, err = km.Sign(slotInfo, testDomain, pubKey1)
+	require.NoError(t, err)
+	reloaded, err = NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	validating, err = reloaded.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, [][48]byte{pubKey1, pubKey2}, validating)
+
+	// Enabled -> enabled is a no-op.
+	require.NoError(t, km.EnableAccounts(ctx, [][48]byte{pubKey1}))
+	require.DeepEqual(t, [][48]byte{pubKey1, pubKey2}, <-pubKeysChan)
+}
+
+func TestKeymanager_DisableAccounts_UnknownKey(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	err = km.DisableAccounts(ctx, [][48]byte{{1}})
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	require.Equal(t, 0, len(km.disabledPublicKeys))
+}
@@ -18,6 +18,71 @@ var ErrNoWalletFound = errors.New(
 "`--wallet-dir=/path/to/my/wallet`",
 )
 
+// ErrWalletExists is returned when attempting to create a wallet in a directory
+// which already contains one.
+var ErrWalletExists = errors.New("wallet already exists at the given directory")
+
+// ErrSeedExists is returned when recovering a mnemonic into a derived wallet which
+// already holds a seed.
+var ErrSeedExists = errors.New("derived wallet already holds a seed")
+
+// ErrWalletLocked is returned when opening a wallet which is already in use by another process.
+var ErrWalletLocked = errors.New("wallet is in use by another process")
+
+// ErrNoKeymanagerFolder is returned when a wallet directory contains no keymanager folder.
+var ErrNoKeymanagerFolder = errors.New("no keymanager folder (imported, remote, derived) found in wallet directory")
+
+// ErrMultipleKeymanagers is returned when a wallet directory contains more than one keymanager folder.
+var ErrMultipleKeymanagers = errors.New("wallet directory contains more than one keymanager folder")
+
+// ErrInsecurePermissions is returned when a wallet file or directory is accessible by other users.
+var ErrInsecurePermissions = errors.New("wallet file is accessible by other users")
+
+// ErrUnreadableKeystore is returned when a keystore or wallet file cannot be read.
+var ErrUnreadableKeystore = errors.New("could not read wallet file")
+
+// ErrCorruptKeystore is returned when a keystore or wallet file cannot be decoded.
+var ErrCorruptKeystore = errors.New("wallet file is corrupt")
+
+// ErrWrongPassword is returned when a keystore cannot be decrypted with the wallet password.
+var ErrWrongPassword = errors.New("wrong password for wallet entered")
+
+// ErrKeyCountMismatch is returned when an accounts keystore holds unequal numbers of
+// private keys and public keys.
+var ErrKeyCountMismatch = errors.New("unequal number of public keys and private keys")
+
+// ErrPublicKeyMismatch is returned when a private key does not derive the public key stored along with it.
+var ErrPublicKeyMismatch = errors.New("private key does not derive its stated public key")
+
+// ErrUnknownPublicKey is returned when a public key does not belong to any account in the wallet.
+var ErrUnknownPublicKey = errors.New("no account found for public key")
+
+// ErrDisabledPublicKey is returned when an operation is attempted on a disabled account.
+var ErrDisabledPublicKey = errors.New("account for public key is disabled")
+
+// ErrUnsupportedKeymanagerKind is returned when no keymanager implementation exists for a wallet's kind.
+var ErrUnsupportedKeymanagerKind = errors.New("unsupported keymanager kind")
+
+// ErrPasswordUnavailable is returned by a password source which has no password to offer.
+var ErrPasswordUnavailable = errors.New("no wallet password available")
+
+// ErrBackupChecksumMismatch is returned when the keystores of a backup archive do not
+// match the checksum recorded in its manifest.
+var ErrBackupChecksumMismatch = errors.New("backup archive does not match its checksum")
+
+// ErrBackupMACMismatch is returned when the MAC of a backup archive does not verify with
+// the backup password, because the archive was altered or the password is wrong.
+var ErrBackupMACMismatch = errors.New("backup archive was altered or the password is wrong")
+
+// ErrAuditLogTampered is returned when the hash chain of an audit log is broken.
+var ErrAuditLogTampered = errors.New("audit log was tampered with")
+
+// ErrAuditLogTruncated is returned when records are missing at the end of an audit log.
+var ErrAuditLogTruncated = errors.New("audit log is truncated")
+
+// ErrKeystoreFileExists is returned when writing a keystore file would replace an existing file.
+var ErrKeystoreFileExists = errors.New("keystore file already exists")
+
 // ErrSigFailedToVerify returns when a signature of a block object(ie attestation, slashing, exit... etc)
 // failed to verify.
 var ErrSigFailedToVerify = errors.New("signature did not verify")
\ No newline at end of file
@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ExportKeystores encrypts each of the selected accounts into a standard EIP-2335
+// keystore under the given password, in the order the public keys were given.
+// Disabled accounts are refused unless includeDisabled is set.
+func (km *Keymanager) ExportKeystores(
+	pubKeys [][48]byte, password string, includeDisabled bool,
+) ([]*Keystore, error) {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	return km.exportKeystores(pubKeys, password, includeDisabled)
+}
+
+// exportKeystores is ExportKeystores for a caller holding km.lock.
+func (km *Keymanager) exportKeystores(
+	pubKeys [][48]byte, password string, includeDisabled bool,
+) ([]*Keystore, error) {
+	privKeysByPubKey := make(map[[48]byte][]byte, len(km.accountsStore.PublicKeys))
+	for i, pubKey := range km.accountsStore.PublicKeys {
+		privKeysByPubKey[bytesutil.ToBytes48(pubKey)] = km.accountsStore.PrivateKeys[i]
+	}
+	encryptor := newKeystoreEncryptor(km.wallet.kdfParams)
+	keystores := make([]*Keystore, 0, len(pubKeys))
+	for _, pubKey := range pubKeys {
+		privKey, ok := privKeysByPubKey[pubKey]
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+		}
+		if km.disabledPublicKeys[pubKey] && !includeDisabled {
+			return nil, errors.Wrapf(ErrDisabledPublicKey, "%#x", pubKey)
+		}
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		cryptoFields, err := encryptor.Encrypt(privKey, password)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not encrypt private key for %#x", pubKey)
+		}
+		keystores = append(keystores, &Keystore{
+			Crypto:  cryptoFields,
+			ID:      id.String(),
+			Pubkey:  fmt.Sprintf("%x", pubKey),
+			Version: encryptor.Version(),
+			Name:    encryptor.Name(),
+		})
+	}
+	return keystores, nil
+}
+
+// WriteKeystoresToDir writes each keystore to its own file in the given directory,
+// named according to KeystoreFileNameFormat. Existing files are never replaced:
+// ErrKeystoreFileExists is returned before anything is written if one of the files is
+// already present.
+func WriteKeystoresToDir(dir string, keystores []*Keystore) error {
+	if err := fileutil.MkdirAll(dir); err != nil {
+		return errors.Wrapf(err, "could not create path: %s", dir)
+	}
+	fullPaths := make([]string, len(keystores))
+	for i := range keystores {
+		fullPaths[i] = filepath.Join(dir, fmt.Sprintf(KeystoreFileNameFormat, i))
+		if fileutil.FileExists(fullPaths[i]) {
+			return errors.Wrap(ErrKeystoreFileExists, fullPaths[i])
+		}
+	}
+	for i, keystore := range keystores {
+		encoded, err := json.MarshalIndent(keystore, "", "\t")
+		if err != nil {
+			return err
+		}
+		// The file may still have been created since it was checked.
+		if err := fileutil.WriteNewFile(fullPaths[i], encoded); os.IsExist(err) {
+			return errors.Wrap(ErrKeystoreFileExists, fullPaths[i])
+		} else if err != nil {
+			return errors.Wrapf(err, "could not write keystore file: %s", fullPaths[i])
+		}
+	}
+	return nil
+}
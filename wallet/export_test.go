@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+func TestKeymanager_ExportKeystores(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore1, secretKey1 := createRandomKeystore(t, "password1")
+	keystore2, secretKey2 := createRandomKeystore(t, "password2")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1, keystore2}, []string{"password1", "password2"}))
+
+	pubKeys := [][48]byte{
+		bytesutil.ToBytes48(secretKey2.PublicKey().Marshal()),
+		bytesutil.ToBytes48(secretKey1.PublicKey().Marshal()),
+	}
+	keystores, err := km.ExportKeystores(pubKeys, "exportpassword", false)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(keystores))
+
+	outputDir := filepath.Join(t.TempDir(), "export")
+	require.NoError(t, WriteKeystoresToDir(outputDir, keystores))
+	written, err := ReadKeystoresFromDir(outputDir)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(written))
+	// Keystores already written are never replaced.
+	err = WriteKeystoresToDir(outputDir, keystores[1:])
+	require.Equal(t, true, errors.Is(err, ErrKeystoreFileExists))
+
+	decryptor := keystorev4.New()
+	privKey, err := decryptor.Decrypt(written[0].Crypto, "exportpassword")
+	require.NoError(t, err)
+	require.DeepEqual(t, secretKey2.Marshal(), privKey)
+	privKey, err = decryptor.Decrypt(written[1].Crypto, "exportpassword")
+	require.NoError(t, err)
+	require.DeepEqual(t, secretKey1.Marshal(), privKey)
+
+	// Exported keystores can be imported into another wallet.
+	other, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	require.NoError(t, other.ImportKeystores(ctx, written, []string{"exportpassword", "exportpassword"}))
+	require.Equal(t, 2, len(other.accountsStore.PublicKeys))
+}
+
+func TestKeymanager_ExportKeystores_DisabledAndUnknown(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	km.disabledPublicKeys[pubKey] = true
+
+	_, err = km.ExportKeystores([][48]byte{pubKey}, "exportpassword", false)
+	require.Equal(t, true, errors.Is(err, ErrDisabledPublicKey))
+
+	keystores, err := km.ExportKeystores([][48]byte{pubKey}, "exportpassword", true)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(keystores))
+
+	_, err = km.ExportKeystores([][48]byte{{1}}, "exportpassword", true)
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+}
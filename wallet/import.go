@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/pkg/errors"
+)
+
+// keystoreFileGlob matches EIP-2335 keystore files, both the ones named after
+// KeystoreFileNameFormat and the ones produced by the eth2 deposit tooling.
+const keystoreFileGlob = "keystore-*.json"
+
+// ImportKeystores into the imported keymanager from an external source. Every
+// keystore is decrypted with the password at the same index, keys which already
+// exist in the accounts store are skipped, and the accounts keystore is re-encrypted
+// with the wallet password and rewritten to disk.
+func (km *Keymanager) ImportKeystores(
+	ctx context.Context,
+	keystores []*Keystore,
+	passwords []string,
+) error {
+	if len(keystores) != len(passwords) {
+		return fmt.Errorf(
+			"number of keystores and passwords is not equal: %d != %d", len(keystores), len(passwords),
+		)
+	}
+	privKeys := make([][]byte, 0, len(keystores))
+	pubKeys := make([][]byte, 0, len(keystores))
+	for i, keystore := range keystores {
+		privKeyBytes, pubKeyBytes, err := decryptKeystore(keystore, passwords[i])
+		if err != nil {
+			return errors.Wrapf(err, "could not import keystore %d", i)
+		}
+		privKeys = append(privKeys, privKeyBytes)
+		pubKeys = append(pubKeys, pubKeyBytes)
+	}
+	km.writeLock.Lock()
+	km.lock.Lock()
+	// addAccounts only appends to the slices of the store, so a shallow copy keeps the
+	// previous store intact to be restored if the accounts cannot be persisted.
+	previousStore := km.accountsStore
+	store := *previousStore
+	km.accountsStore = &store
+	imported := km.addAccounts(privKeys, pubKeys)
+	err := km.initializeKeysCachesFromKeystore()
+	km.lock.Unlock()
+	if err == nil {
+		err = km.writeAccountsKeystore(ctx)
+	}
+	if err != nil {
+		km.lock.Lock()
+		km.accountsStore = previousStore
+		if cacheErr := km.initializeKeysCachesFromKeystore(); cacheErr != nil {
+			log.WithError(cacheErr).Error("Could not restore keys caches")
+		}
+		km.lock.Unlock()
+		km.writeLock.Unlock()
+		return errors.Wrap(err, "could not import keystores")
+	}
+	km.writeLock.Unlock()
+	log.WithField("numAccounts", imported).Info("Imported keystores into wallet")
+	if imported > 0 {
+		pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+		km.accountsChangedFeed.Send(pubKeys)
+	}
+	return nil
+}
+
+// ReadKeystoresFromDir reads every EIP-2335 keystore file found in a directory.
+func ReadKeystoresFromDir(dir string) ([]*Keystore, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, keystoreFileGlob))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not find keystore files")
+	}
+	keystores := make([]*Keystore, 0, len(matches))
+	for _, match := range matches {
+		encoded, err := ioutil.ReadFile(match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read keystore file: %s", match)
+		}
+		keystore := &Keystore{}
+		if err := json.Unmarshal(encoded, keystore); err != nil {
+			return nil, errors.Wrapf(err, "could not decode keystore file: %s", match)
+		}
+		keystores = append(keystores, keystore)
+	}
+	return keystores, nil
+}
+
+// addAccounts appends the given keys to the accounts store, created now, skipping
+// the ones whose public key is already present. Returns the number of keys added.
+// The caller must hold km.lock for writing.
+func (km *Keymanager) addAccounts(privKeys, pubKeys [][]byte) int {
+	existingPubKeys := make(map[string]bool, len(km.accountsStore.PublicKeys))
+	for _, pubKey := range km.accountsStore.PublicKeys {
+		existingPubKeys[string(pubKey)] = true
+	}
+	added := 0
+	now := time.Now().Unix()
+	for i := range privKeys {
+		if existingPubKeys[string(pubKeys[i])] {
+			continue
+		}
+		existingPubKeys[string(pubKeys[i])] = true
+		km.accountsStore.PrivateKeys = append(km.accountsStore.PrivateKeys, privKeys[i])
+		km.accountsStore.PublicKeys = append(km.accountsStore.PublicKeys, pubKeys[i])
+		km.accountsStore.CreationTimes = append(km.accountsStore.CreationTimes, now)
+		added++
+	}
+	return added
+}
+
+// writeAccountsKeystore encrypts the current accounts store with the wallet
+// password and writes it to the accounts keystore file.
+func (km *Keymanager) writeAccountsKeystore(ctx context.Context) error {
+	km.wallet.passwordLock.RLock()
+	defer km.wallet.passwordLock.RUnlock()
+	km.lock.RLock()
+	accountsKeystore, err := newAccountsKeystore(
+		km.accountsStore, km.wallet.walletPassword, km.disabledPublicKeys, km.wallet.kdfParams,
+	)
+	km.lock.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "could not create accounts keystore")
+	}
+	encodedAccounts, err := json.MarshalIndent(accountsKeystore, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := km.wallet.WriteFileAtPath(ctx, AccountsPath, AccountsKeystoreFileName, encodedAccounts); err != nil {
+		return errors.Wrap(err, "could not write accounts keystore")
+	}
+	return nil
+}
+
+// decryptKeystore retrieves the private key from an EIP-2335 keystore and derives
+// its public key, checking it against the public key stated in the keystore if any.
+func decryptKeystore(keystore *Keystore, password string) ([]byte, []byte, error) {
+	privKeyBytes, err := decryptKeystoreCrypto(keystore.Crypto, password)
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, nil, errors.Errorf("wrong password for keystore %s", keystore.ID)
+	} else if err != nil {
+		return nil, nil, errors.Wrap(err, "could not decrypt keystore")
+	}
+	privKey, err := bls.SecretKeyFromBytes(privKeyBytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not initialize private key from bytes")
+	}
+	pubKeyBytes := privKey.PublicKey().Marshal()
+	if keystore.Pubkey != "" {
+		stated, err := hex.DecodeString(strings.TrimPrefix(keystore.Pubkey, "0x"))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not decode keystore public key")
+		}
+		if !bytes.Equal(stated, pubKeyBytes) {
+			return nil, nil, fmt.Errorf("keystore public key %#x does not match its private key", stated)
+		}
+	}
+	return privKeyBytes, pubKeyBytes, nil
+}
@@ -0,0 +1,136 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/google/uuid"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+const testWalletPassword = "Passwordz0320$"
+
+func setupImportedWallet(t testing.TB) *Wallet {
+	w, err := CreateWallet(context.Background(), &Config{
+		WalletDir:      filepath.Join(t.TempDir(), "wallet"),
+		KeymanagerKind: Imported,
+		WalletPassword: testWalletPassword,
+		KDFParams:      FastKDFParams(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, w.Close())
+	})
+	return w
+}
+
+func createRandomKeystore(t testing.TB, password string) (*Keystore, bls.SecretKey) {
+	encryptor := keystorev4.New()
+	id, err := uuid.NewRandom()
+	require.NoError(t, err)
+	validatingKey, err := bls.RandKey()
+	require.NoError(t, err)
+	pubKey := validatingKey.PublicKey().Marshal()
+	cryptoFields, err := encryptor.Encrypt(validatingKey.Marshal(), password)
+	require.NoError(t, err)
+	return &Keystore{
+		Crypto:  cryptoFields,
+		Pubkey:  fmt.Sprintf("%x", pubKey),
+		ID:      id.String(),
+		Version: encryptor.Version(),
+		Name:    encryptor.Name(),
+	}, validatingKey
+}
+
+func TestKeymanager_ImportKeystores(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+
+	keystore1, _ := createRandomKeystore(t, "password1")
+	keystore2, _ := createRandomKeystore(t, "password2")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1, keystore2}, []string{"password1", "password2"}))
+	require.Equal(t, 2, len(km.accountsStore.PublicKeys))
+
+	// Importing the same keystore again should not create a duplicate account.
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1}, []string{"password1"}))
+	require.Equal(t, 2, len(km.accountsStore.PublicKeys))
+
+	// The imported accounts are persisted to disk under the wallet password.
+	reloaded, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.DeepEqual(t, km.accountsStore.PublicKeys, reloaded.accountsStore.PublicKeys)
+	require.DeepEqual(t, km.accountsStore.PrivateKeys, reloaded.accountsStore.PrivateKeys)
+	require.Equal(t, fmt.Sprintf("%x", reloaded.accountsStore.PublicKeys[0]), keystore1.Pubkey)
+}
+
+func TestKeymanager_ImportKeystores_WriteFailure(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore1, _ := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1}, []string{"password"}))
+
+	// The accounts keystore cannot be replaced while it has other permissions than 0600.
+	keystorePath := filepath.Join(w.accountsPath, AccountsPath, AccountsKeystoreFileName)
+	require.NoError(t, os.Chmod(keystorePath, 0644))
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	err = km.ImportKeystores(ctx, []*Keystore{keystore2}, []string{"password"})
+	require.ErrorContains(t, "could not import keystores", err)
+	require.Equal(t, 1, len(km.accountsStore.PublicKeys))
+	require.Equal(t, 1, len(km.orderedPublicKeys))
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, bytesutil.ToBytes48(secretKey2.PublicKey().Marshal()))
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+
+	require.NoError(t, os.Chmod(keystorePath, 0600))
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore2}, []string{"password"}))
+	require.Equal(t, 2, len(km.orderedPublicKeys))
+}
+
+func TestKeymanager_ImportKeystores_WrongPassword(t *testing.T) {
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(context.Background(), w)
+	require.NoError(t, err)
+
+	keystore, _ := createRandomKeystore(t, "password")
+	err = km.ImportKeystores(context.Background(), []*Keystore{keystore}, []string{"wrong"})
+	require.ErrorContains(t, "wrong password for keystore", err)
+	require.Equal(t, 0, len(km.accountsStore.PublicKeys))
+}
+
+func TestKeymanager_ImportKeystores_PublicKeyMismatch(t *testing.T) {
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(context.Background(), w)
+	require.NoError(t, err)
+
+	keystore, _ := createRandomKeystore(t, "password")
+	other, _ := createRandomKeystore(t, "password")
+	keystore.Pubkey = other.Pubkey
+	err = km.ImportKeystores(context.Background(), []*Keystore{keystore}, []string{"password"})
+	require.ErrorContains(t, "does not match its private key", err)
+}
+
+func TestReadKeystoresFromDir(t *testing.T) {
+	dir := t.TempDir()
+	keystore, _ := createRandomKeystore(t, "password")
+	encoded, err := json.Marshal(keystore)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf(KeystoreFileNameFormat, 0)), encoded, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a keystore"), 0600))
+
+	keystores, err := ReadKeystoresFromDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(keystores))
+	require.DeepEqual(t, keystore, keystores[0])
+}
@@ -0,0 +1,29 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// IKeymanager defines a general keymanager interface, implemented by the imported,
+// derived and remote keymanagers, so consumers do not depend on a concrete backend.
+type IKeymanager interface {
+	// FetchValidatingPublicKeys fetches the list of public keys that should be used to validate with.
+	FetchValidatingPublicKeys(ctx context.Context) ([][48]byte, error)
+	// Sign signs the signing root of the slot info in the domain with the validating key
+	// of the given public key.
+	Sign(slotInfo *SlotInfo, domain Domain, pubKey [48]byte) (bls.Signature, error)
+	// VerifySignature verifies a signature over the slot info in the domain with the given public key.
+	VerifySignature(slotInfo *SlotInfo, domain Domain, pubKey [48]byte, signature bls.Signature) error
+	// SubscribeAccountChanges notifies the subscriber with the full list of public keys
+	// whenever the accounts of the keymanager change.
+	SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription
+}
+
+var (
+	_ IKeymanager = (*Keymanager)(nil)
+	_ IKeymanager = (*DerivedKeymanager)(nil)
+	_ IKeymanager = (*RemoteKeymanager)(nil)
+)
@@ -0,0 +1,442 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+// KDF is the key derivation function turning a password into the key which encrypts
+// the secret of an EIP-2335 keystore.
+type KDF int
+
+const (
+	// PBKDF2 with HMAC-SHA256.
+	PBKDF2 KDF = iota
+	// Scrypt, memory-hard.
+	Scrypt
+)
+
+const (
+	// Name and version of the keystores written by wallets, as defined by EIP-2335.
+	keystoreName    = "keystore"
+	keystoreVersion = 4
+	// Length of the derived key, split between the cipher key and the checksum key.
+	kdfKeyLen = 32
+)
+
+// KDFParams selects the KDF and cost parameters used when a wallet writes keystores.
+// Keystores are always read with the parameters stored in them. The parameters a
+// wallet is created with are saved in its keymanager options.
+type KDFParams struct {
+	Function KDF `json:"function"`
+	// C is the PBKDF2 iteration count.
+	C int `json:"c,omitempty"`
+	// N, R and P are the scrypt CPU/memory cost, block size and parallelization.
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+}
+
+// DefaultKDFParams for keystores written by wallets: PBKDF2 with the iteration count
+// of the EIP-2335 test vectors, as the keystores written by the eth2 deposit tooling.
+func DefaultKDFParams() *KDFParams {
+	return &KDFParams{Function: PBKDF2, C: 262144}
+}
+
+// ScryptKDFParams with the cost of the EIP-2335 scrypt test vectors.
+func ScryptKDFParams() *KDFParams {
+	return &KDFParams{Function: Scrypt, N: 262144, R: 8, P: 1}
+}
+
+// FastKDFParams are cheap to compute and only meant for tests and CI, where keystores
+// are written and read many times. They offer no protection against brute force.
+func FastKDFParams() *KDFParams {
+	return &KDFParams{Function: PBKDF2, C: 16}
+}
+
+// ParseKDF from a raw string, as in the function field of a keystore.
+func ParseKDF(kdf string) (KDF, error) {
+	switch kdf {
+	case "pbkdf2":
+		return PBKDF2, nil
+	case "scrypt":
+		return Scrypt, nil
+	default:
+		return 0, fmt.Errorf("%s is not a supported KDF", kdf)
+	}
+}
+
+// String marshals a KDF to its name in keystores.
+func (k KDF) String() string {
+	switch k {
+	case PBKDF2:
+		return "pbkdf2"
+	case Scrypt:
+		return "scrypt"
+	default:
+		return fmt.Sprintf("%d", int(k))
+	}
+}
+
+// MarshalText encodes a KDF by its name in keystores.
+func (k KDF) MarshalText() ([]byte, error) {
+	if k != PBKDF2 && k != Scrypt {
+		return nil, fmt.Errorf("unsupported KDF %s", k)
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a KDF from its name in keystores.
+func (k *KDF) UnmarshalText(text []byte) error {
+	kdf, err := ParseKDF(string(text))
+	if err != nil {
+		return err
+	}
+	*k = kdf
+	return nil
+}
+
+// cost of the KDF, comparable between parameters of the same function only.
+func (p *KDFParams) cost() int {
+	if p.Function == Scrypt {
+		return p.N * p.R * p.P
+	}
+	return p.C
+}
+
+// weakerThan returns true if keystores written with the parameters are cheaper to brute
+// force than with the other ones. Scrypt, being memory-hard, is stronger than PBKDF2
+// whatever their costs.
+func (p *KDFParams) weakerThan(other *KDFParams) bool {
+	if p.Function != other.Function {
+		return p.Function == PBKDF2 && other.Function == Scrypt
+	}
+	return p.cost() < other.cost()
+}
+
+func (p *KDFParams) validate() error {
+	switch p.Function {
+	case PBKDF2:
+		if p.C < 1 {
+			return errors.New("PBKDF2 iteration count must be positive")
+		}
+	case Scrypt:
+		if p.N <= 1 || p.N&(p.N-1) != 0 {
+			return errors.New("scrypt N must be a power of 2 greater than 1")
+		}
+		if p.R < 1 || p.P < 1 {
+			return errors.New("scrypt r and p must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported KDF %s", p.Function)
+	}
+	return nil
+}
+
+// deriveKey derives a key of kdfKeyLen bytes from the password and salt, normalizing
+// the password as keystores do.
+func (p *KDFParams) deriveKey(password string, salt []byte) ([]byte, error) {
+	normedPassword := []byte(normalizeKeystorePassword(password))
+	if p.Function == Scrypt {
+		key, err := scrypt.Key(normedPassword, salt, p.N, p.R, p.P, kdfKeyLen)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not derive key")
+		}
+		return key, nil
+	}
+	return pbkdf2.Key(normedPassword, salt, p.C, kdfKeyLen, sha256.New), nil
+}
+
+// keystoreEncryptor writes the crypto field of EIP-2335 keystores with configurable KDF
+// parameters. The resulting keystores can be read with keystorev4, like any other.
+type keystoreEncryptor struct {
+	params *KDFParams
+}
+
+func newKeystoreEncryptor(params *KDFParams) *keystoreEncryptor {
+	if params == nil {
+		params = DefaultKDFParams()
+	}
+	return &keystoreEncryptor{params: params}
+}
+
+// Name of the keystores written.
+func (e *keystoreEncryptor) Name() string {
+	return keystoreName
+}
+
+// Version of the keystores written.
+func (e *keystoreEncryptor) Version() uint {
+	return keystoreVersion
+}
+
+// Encrypt the secret with the password into the crypto field of a keystore, using
+// AES-128-CTR with a key derived from the password and a SHA256 checksum.
+func (e *keystoreEncryptor) Encrypt(secret []byte, password string) (map[string]interface{}, error) {
+	if err := e.params.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid KDF parameters")
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := e.params.deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	kdfParams := map[string]interface{}{
+		"dklen": kdfKeyLen,
+		"salt":  hex.EncodeToString(salt),
+	}
+	switch e.params.Function {
+	case Scrypt:
+		kdfParams["n"], kdfParams["r"], kdfParams["p"] = e.params.N, e.params.R, e.params.P
+	default:
+		kdfParams["c"], kdfParams["prf"] = e.params.C, "hmac-sha256"
+	}
+
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipherMsg := make([]byte, len(secret))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherMsg, secret)
+	checksum := sha256.Sum256(append(append([]byte{}, key[16:32]...), cipherMsg...))
+
+	crypto := map[string]interface{}{
+		"kdf": map[string]interface{}{
+			"function": e.params.Function.String(),
+			"params":   kdfParams,
+			"message":  "",
+		},
+		"checksum": map[string]interface{}{
+			"function": "sha256",
+			"params":   map[string]interface{}{},
+			"message":  hex.EncodeToString(checksum[:]),
+		},
+		"cipher": map[string]interface{}{
+			"function": "aes-128-ctr",
+			"params":   map[string]interface{}{"iv": hex.EncodeToString(iv)},
+			"message":  hex.EncodeToString(cipherMsg),
+		},
+	}
+	// Go through JSON to hand out the same generic map as a decoded keystore.
+	encoded, err := json.Marshal(crypto)
+	if err != nil {
+		return nil, err
+	}
+	cryptoFields := make(map[string]interface{})
+	if err := json.Unmarshal(encoded, &cryptoFields); err != nil {
+		return nil, err
+	}
+	return cryptoFields, nil
+}
+
+// normalizeKeystorePassword as keystorev4 does before deriving a key: NFKD normalization
+// and removal of the control codes encoded on a single byte.
+func normalizeKeystorePassword(password string) string {
+	var normalized []byte
+	iter := &norm.Iter{}
+	iter.InitString(norm.NFKD, password)
+	for !iter.Done() {
+		r, size := utf8.DecodeRune(iter.Next())
+		if size == 1 && (r < 0x20 || r == 0x7f) {
+			continue
+		}
+		buf := make([]byte, utf8.RuneLen(r))
+		utf8.EncodeRune(buf, r)
+		normalized = norm.NFKD.Append(normalized, buf...)
+	}
+	return string(normalized)
+}
+
+// keystoreCrypto is the crypto field of an EIP-2335 keystore.
+type keystoreCrypto struct {
+	KDF struct {
+		Function string `json:"function"`
+		Params   struct {
+			DKLen int    `json:"dklen"`
+			Salt  string `json:"salt"`
+			C     int    `json:"c"`
+			PRF   string `json:"prf"`
+			N     int    `json:"n"`
+			R     int    `json:"r"`
+			P     int    `json:"p"`
+		} `json:"params"`
+	} `json:"kdf"`
+	Checksum struct {
+		Function string `json:"function"`
+		Message  string `json:"message"`
+	} `json:"checksum"`
+	Cipher struct {
+		Function string `json:"function"`
+		Params   struct {
+			IV string `json:"iv"`
+		} `json:"params"`
+		Message string `json:"message"`
+	} `json:"cipher"`
+}
+
+func decodeKeystoreCrypto(cryptoFields map[string]interface{}) (*keystoreCrypto, error) {
+	encoded, err := json.Marshal(cryptoFields)
+	if err != nil {
+		return nil, err
+	}
+	crypto := &keystoreCrypto{}
+	if err := json.Unmarshal(encoded, crypto); err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore crypto")
+	}
+	return crypto, nil
+}
+
+// keystoreKDFParams reads the KDF and its cost parameters from the crypto field of a keystore.
+func keystoreKDFParams(cryptoFields map[string]interface{}) (*KDFParams, error) {
+	crypto, err := decodeKeystoreCrypto(cryptoFields)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.kdfParams()
+}
+
+func (c *keystoreCrypto) kdfParams() (*KDFParams, error) {
+	function, err := ParseKDF(c.KDF.Function)
+	if err != nil {
+		return nil, err
+	}
+	return &KDFParams{
+		Function: function,
+		C:        c.KDF.Params.C,
+		N:        c.KDF.Params.N,
+		R:        c.KDF.Params.R,
+		P:        c.KDF.Params.P,
+	}, nil
+}
+
+// decryptKeystoreCrypto decrypts the secret in the crypto field of an EIP-2335 keystore
+// with the password. The checksum of the keystore is compared with the one computed from
+// the key derived from the password first, returning ErrWrongPassword if they differ.
+func decryptKeystoreCrypto(cryptoFields map[string]interface{}, password string) ([]byte, error) {
+	crypto, err := decodeKeystoreCrypto(cryptoFields)
+	if err != nil {
+		return nil, err
+	}
+	params, err := crypto.kdfParams()
+	if err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid keystore KDF parameters")
+	}
+	if crypto.KDF.Params.DKLen != kdfKeyLen {
+		return nil, fmt.Errorf("unsupported keystore KDF key length %d", crypto.KDF.Params.DKLen)
+	}
+	if params.Function == PBKDF2 && crypto.KDF.Params.PRF != "hmac-sha256" {
+		return nil, fmt.Errorf("unsupported keystore PBKDF2 PRF %s", crypto.KDF.Params.PRF)
+	}
+	if crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("unsupported keystore checksum %s", crypto.Checksum.Function)
+	}
+	if crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher %s", crypto.Cipher.Function)
+	}
+	salt, err := hex.DecodeString(crypto.KDF.Params.Salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore salt")
+	}
+	checksum, err := hex.DecodeString(crypto.Checksum.Message)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore checksum")
+	}
+	iv, err := hex.DecodeString(crypto.Cipher.Params.IV)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore cipher IV")
+	}
+	cipherMsg, err := hex.DecodeString(crypto.Cipher.Message)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore cipher message")
+	}
+
+	key, err := params.deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	computed := sha256.Sum256(append(append([]byte{}, key[16:32]...), cipherMsg...))
+	if subtle.ConstantTimeCompare(computed[:], checksum) != 1 {
+		return nil, ErrWrongPassword
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid keystore cipher IV length %d", len(iv))
+	}
+	secret := make([]byte, len(cipherMsg))
+	cipher.NewCTR(block, iv).XORKeyStream(secret, cipherMsg)
+	return secret, nil
+}
+
+// warnIfWeakerKDF logs a warning when a keystore read by the wallet was written with
+// weaker KDF parameters than configured for the wallet. Such keystores are upgraded the
+// next time the wallet rewrites them.
+func (w *Wallet) warnIfWeakerKDF(path string, cryptoFields map[string]interface{}) {
+	configured := w.kdfParams
+	if configured == nil {
+		configured = DefaultKDFParams()
+	}
+	params, err := keystoreKDFParams(cryptoFields)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Warn("Could not read keystore KDF parameters")
+		return
+	}
+	if params.weakerThan(configured) {
+		log.WithFields(logrus.Fields{
+			"path":       path,
+			"configured": configured.Function,
+		}).Warnf("Keystore uses weaker %s parameters than configured", params.Function)
+	}
+}
+
+// readKDFParams reads the KDF parameters saved in the keymanager options of a wallet
+// when it was created. Returns nil for wallets created before they were saved.
+func readKDFParams(accountsPath string) (*KDFParams, error) {
+	configFilePath := filepath.Join(accountsPath, KeymanagerConfigFileName)
+	if !fileutil.FileExists(configFilePath) {
+		return nil, nil
+	}
+	encoded, err := fileutil.ReadFileAsBytes(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	opts := &struct {
+		KDFParams *KDFParams `json:"kdf_params"`
+	}{}
+	if err := json.Unmarshal(encoded, opts); err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", configFilePath)
+	}
+	if opts.KDFParams != nil {
+		if err := opts.KDFParams.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid KDF parameters in %s", configFilePath)
+		}
+	}
+	return opts.KDFParams, nil
+}
@@ -0,0 +1,201 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+	logTest "github.com/sirupsen/logrus/hooks/test"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+func TestKeystoreEncryptor_DecryptsWithKeystorev4(t *testing.T) {
+	secret := []byte("secret to encrypt in the keystore")
+	// Password of the EIP-2335 test vectors, whose normalization drops the control code.
+	password := "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511\x7f"
+	for _, params := range []*KDFParams{
+		FastKDFParams(),
+		{Function: PBKDF2, C: 1024},
+		{Function: Scrypt, N: 1024, R: 8, P: 1},
+	} {
+		t.Run(params.Function.String(), func(t *testing.T) {
+			encryptor := newKeystoreEncryptor(params)
+			cryptoFields, err := encryptor.Encrypt(secret, password)
+			require.NoError(t, err)
+			decrypted, err := keystorev4.New().Decrypt(cryptoFields, password)
+			require.NoError(t, err)
+			require.DeepEqual(t, secret, decrypted)
+			_, err = keystorev4.New().Decrypt(cryptoFields, "wrong password")
+			require.ErrorContains(t, "invalid checksum", err)
+
+			read, err := keystoreKDFParams(cryptoFields)
+			require.NoError(t, err)
+			require.DeepEqual(t, params, read)
+		})
+	}
+}
+
+func TestDecryptKeystoreCrypto(t *testing.T) {
+	secret := []byte("secret to encrypt in the keystore")
+	password := "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511\x7f"
+	written, err := keystorev4.New().Encrypt(secret, password)
+	require.NoError(t, err)
+	fast, err := newKeystoreEncryptor(FastKDFParams()).Encrypt(secret, password)
+	require.NoError(t, err)
+	scrypt, err := newKeystoreEncryptor(&KDFParams{Function: Scrypt, N: 1024, R: 8, P: 1}).Encrypt(secret, password)
+	require.NoError(t, err)
+
+	for name, cryptoFields := range map[string]map[string]interface{}{
+		"keystorev4": written,
+		"pbkdf2":     fast,
+		"scrypt":     scrypt,
+	} {
+		t.Run(name, func(t *testing.T) {
+			decrypted, err := decryptKeystoreCrypto(cryptoFields, password)
+			require.NoError(t, err)
+			require.DeepEqual(t, secret, decrypted)
+			_, err = decryptKeystoreCrypto(cryptoFields, "wrong password")
+			require.Equal(t, ErrWrongPassword, err)
+		})
+	}
+
+	// The checksum covers the cipher message, so an altered secret is reported like a
+	// wrong password rather than decrypted to garbage.
+	encoded, err := json.Marshal(fast)
+	require.NoError(t, err)
+	altered := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(encoded, &altered))
+	cipherFields := altered["cipher"].(map[string]interface{})
+	message, err := hex.DecodeString(cipherFields["message"].(string))
+	require.NoError(t, err)
+	message[0] ^= 1
+	cipherFields["message"] = hex.EncodeToString(message)
+	_, err = decryptKeystoreCrypto(altered, password)
+	require.Equal(t, ErrWrongPassword, err)
+
+	cipherFields["function"] = "aes-256-gcm"
+	_, err = decryptKeystoreCrypto(altered, password)
+	require.ErrorContains(t, "unsupported keystore cipher aes-256-gcm", err)
+}
+
+func TestKeystoreEncryptor_InvalidParams(t *testing.T) {
+	for _, params := range []*KDFParams{
+		{Function: PBKDF2},
+		{Function: Scrypt, N: 1000, R: 8, P: 1},
+		{Function: Scrypt, N: 1024},
+		{Function: KDF(7), C: 16},
+	} {
+		_, err := newKeystoreEncryptor(params).Encrypt([]byte("secret"), "password")
+		require.ErrorContains(t, "invalid KDF parameters", err)
+	}
+}
+
+func TestWallet_KDFParams(t *testing.T) {
+	ctx := context.Background()
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	w, err := CreateWallet(ctx, &Config{
+		WalletDir:      walletDir,
+		KeymanagerKind: Imported,
+		WalletPassword: testWalletPassword,
+		KDFParams:      &KDFParams{Function: Scrypt, N: 1024, R: 8, P: 1},
+	})
+	require.NoError(t, err)
+	encoded, err := w.ReadFileAtPath(AccountsPath, AccountsKeystoreFileName)
+	require.NoError(t, err)
+	keystore := &AccountsKeystoreRepresentation{}
+	require.NoError(t, json.Unmarshal(encoded, keystore))
+	params, err := keystoreKDFParams(keystore.Crypto)
+	require.NoError(t, err)
+	require.DeepEqual(t, &KDFParams{Function: Scrypt, N: 1024, R: 8, P: 1}, params)
+	require.NoError(t, w.Close())
+
+	hook := logTest.NewGlobal()
+	w, err = OpenWallet(ctx, &Config{
+		WalletDir:      walletDir,
+		WalletPassword: testWalletPassword,
+		KDFParams:      &KDFParams{Function: Scrypt, N: 2048, R: 8, P: 1},
+	})
+	require.NoError(t, err)
+	_, err = NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.LogsContain(t, hook, "Keystore uses weaker scrypt parameters than configured")
+	require.NoError(t, w.Close())
+
+	hook.Reset()
+	w, err = OpenWallet(ctx, &Config{
+		WalletDir:      walletDir,
+		WalletPassword: testWalletPassword,
+		KDFParams:      FastKDFParams(),
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+	_, err = NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	require.LogsDoNotContain(t, hook, "Keystore uses weaker")
+	require.LogsContain(t, hook, "weaker pbkdf2 parameters than the scrypt ones the wallet was created with")
+}
+
+func TestWallet_SavedKDFParams(t *testing.T) {
+	ctx := context.Background()
+	params := &KDFParams{Function: Scrypt, N: 1024, R: 8, P: 1}
+	for _, kind := range []Kind{Imported, Derived} {
+		t.Run(kind.String(), func(t *testing.T) {
+			walletDir := filepath.Join(t.TempDir(), "wallet")
+			w, err := CreateWallet(ctx, &Config{
+				WalletDir:      walletDir,
+				KeymanagerKind: kind,
+				WalletPassword: testWalletPassword,
+				KDFParams:      params,
+			})
+			require.NoError(t, err)
+			if kind == Derived {
+				mnemonic, err := GenerateMnemonic()
+				require.NoError(t, err)
+				_, err = RecoverFromMnemonic(ctx, w, mnemonic, "", 1, false /* overwrite */)
+				require.NoError(t, err)
+			}
+			require.NoError(t, w.Close())
+
+			// Keystores rewritten by a wallet opened without KDF parameters keep the
+			// ones the wallet was created with.
+			hook := logTest.NewGlobal()
+			w, err = OpenWallet(ctx, &Config{WalletDir: walletDir, WalletPassword: testWalletPassword})
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, w.Close())
+			}()
+			require.DeepEqual(t, params, w.kdfParams)
+			var filePath, fileName string
+			if kind == Derived {
+				km, err := NewDerivedKeymanager(ctx, w)
+				require.NoError(t, err)
+				_, err = km.CreateAccount(ctx)
+				require.NoError(t, err)
+				fileName = EncryptedSeedFileName
+			} else {
+				km, err := NewImportedKeymanager(ctx, w)
+				require.NoError(t, err)
+				keystore, _ := createRandomKeystore(t, "password")
+				require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+				filePath, fileName = AccountsPath, AccountsKeystoreFileName
+			}
+			require.NoError(t, w.ChangePassword(ctx, testWalletPassword, "NewPassw0rdz2020!"))
+			require.LogsDoNotContain(t, hook, "weaker")
+
+			encoded, err := w.ReadFileAtPath(filePath, fileName)
+			require.NoError(t, err)
+			keystore := &struct {
+				Crypto map[string]interface{} `json:"crypto"`
+			}{}
+			require.NoError(t, json.Unmarshal(encoded, keystore))
+			written, err := keystoreKDFParams(keystore.Crypto)
+			require.NoError(t, err)
+			require.DeepEqual(t, params, written)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// The BLS12-381 curve order, which derived secret keys are reduced modulo.
+var curveOrder, _ = new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+
+// Number of 32 byte chunks in a lamport secret key, as defined by EIP-2333.
+const lamportChunks = 255
+
+// DeriveMasterSK derives the master secret key from a seed of at least 32 bytes.
+//
+// In EIP-2333:
+// def derive_master_SK(seed: bytes) -> int
+func DeriveMasterSK(seed []byte) (*big.Int, error) {
+	if len(seed) < 32 {
+		return nil, errors.New("seed must be at least 32 bytes")
+	}
+	return hkdfModR(seed, nil)
+}
+
+// DeriveChildSK derives the child secret key at the given index from its parent.
+//
+// In EIP-2333:
+// def derive_child_SK(parent_SK: int, index: int) -> int
+func DeriveChildSK(parentSK *big.Int, index uint32) (*big.Int, error) {
+	lamportPK, err := parentSKToLamportPK(parentSK, index)
+	if err != nil {
+		return nil, err
+	}
+	return hkdfModR(lamportPK, nil)
+}
+
+// PrivateKeyFromSeedAndPath derives the 32 byte, big-endian secret key found at an
+// EIP-2334 path such as m/12381/3600/0/0/0 starting from the given seed.
+func PrivateKeyFromSeedAndPath(seed []byte, path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("no path")
+	}
+	pathBits := strings.Split(path, "/")
+	if pathBits[0] != "m" {
+		return nil, fmt.Errorf("path %s does not start with m", path)
+	}
+	sk, err := DeriveMasterSK(seed)
+	if err != nil {
+		return nil, err
+	}
+	for i, bit := range pathBits[1:] {
+		if bit == "" {
+			return nil, fmt.Errorf("no entry at path component %d", i+1)
+		}
+		index, err := strconv.ParseUint(bit, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q at path component %d", bit, i+1)
+		}
+		sk, err = DeriveChildSK(sk, uint32(index))
+		if err != nil {
+			return nil, err
+		}
+	}
+	skBytes := make([]byte, 32)
+	return sk.FillBytes(skBytes), nil
+}
+
+// In EIP-2333:
+// def HKDF_mod_r(IKM: bytes, key_info: bytes=b"") -> int
+func hkdfModR(ikm []byte, keyInfo []byte) (*big.Int, error) {
+	const okmLength = 48
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	sk := new(big.Int)
+	for sk.Sign() == 0 {
+		hashedSalt := sha256.Sum256(salt)
+		salt = hashedSalt[:]
+		prk := hkdf.Extract(sha256.New, append(append([]byte{}, ikm...), 0), salt)
+		info := append(append([]byte{}, keyInfo...), 0, okmLength)
+		okm := make([]byte, okmLength)
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), okm); err != nil {
+			return nil, errors.Wrap(err, "could not expand key material")
+		}
+		sk.Mod(new(big.Int).SetBytes(okm), curveOrder)
+	}
+	return sk, nil
+}
+
+// In EIP-2333:
+// def parent_SK_to_lamport_PK(parent_SK: int, index: int) -> bytes
+func parentSKToLamportPK(parentSK *big.Int, index uint32) ([]byte, error) {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+	ikm := parentSK.FillBytes(make([]byte, 32))
+	notIKM := make([]byte, len(ikm))
+	for i := range ikm {
+		notIKM[i] = ^ikm[i]
+	}
+	lamport0, err := ikmToLamportSK(ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	lamport1, err := ikmToLamportSK(notIKM, salt)
+	if err != nil {
+		return nil, err
+	}
+	lamportPK := make([]byte, 0, 2*lamportChunks*32)
+	for _, chunks := range [][][]byte{lamport0, lamport1} {
+		for _, chunk := range chunks {
+			hashedChunk := sha256.Sum256(chunk)
+			lamportPK = append(lamportPK, hashedChunk[:]...)
+		}
+	}
+	compressedLamportPK := sha256.Sum256(lamportPK)
+	return compressedLamportPK[:], nil
+}
+
+// In EIP-2333:
+// def IKM_to_lamport_SK(IKM: bytes, salt: bytes) -> List[bytes]
+func ikmToLamportSK(ikm []byte, salt []byte) ([][]byte, error) {
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	okm := make([]byte, lamportChunks*32)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, nil), okm); err != nil {
+		return nil, errors.Wrap(err, "could not expand key material")
+	}
+	lamportSK := make([][]byte, lamportChunks)
+	for i := range lamportSK {
+		lamportSK[i] = okm[i*32 : (i+1)*32]
+	}
+	return lamportSK, nil
+}
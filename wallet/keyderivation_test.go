@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+// Test vectors from https://eips.ethereum.org/EIPS/eip-2333#test-cases.
+func TestDeriveChildSK_EIP2333Vectors(t *testing.T) {
+	tests := []struct {
+		seed       string
+		masterSK   string
+		childIndex uint32
+		childSK    string
+	}{
+		{
+			seed:       "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+			masterSK:   "6083874454709270928345386274498605044986640685124978867557563392430687146096",
+			childIndex: 0,
+			childSK:    "20397789859736650942317412262472558107875392172444076792671091975210932703118",
+		},
+		{
+			seed:       "3141592653589793238462643383279502884197169399375105820974944592",
+			masterSK:   "29757020647961307431480504535336562678282505419141012933316116377660817309383",
+			childIndex: 3141592653,
+			childSK:    "25457201688850691947727629385191704516744796114925897962676248250929345014287",
+		},
+		{
+			seed:       "0099FF991111002299DD7744EE3355BBDD8844115566CC55663355668888CC00",
+			masterSK:   "27580842291869792442942448775674722299803720648445448686099262467207037398656",
+			childIndex: 4294967295,
+			childSK:    "29358610794459428860402234341874281240803786294062035874021252734817515685787",
+		},
+		{
+			seed:       "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+			masterSK:   "19022158461524446591288038168518313374041767046816487870552872741050760015818",
+			childIndex: 42,
+			childSK:    "31372231650479070279774297061823572166496564838472787488249775572789064611981",
+		},
+	}
+	for _, tt := range tests {
+		seed, err := hex.DecodeString(tt.seed)
+		require.NoError(t, err)
+		masterSK, err := DeriveMasterSK(seed)
+		require.NoError(t, err)
+		require.Equal(t, tt.masterSK, masterSK.String())
+		childSK, err := DeriveChildSK(masterSK, tt.childIndex)
+		require.NoError(t, err)
+		require.Equal(t, tt.childSK, childSK.String())
+	}
+}
+
+func TestPrivateKeyFromSeedAndPath(t *testing.T) {
+	seed, err := hex.DecodeString("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	require.NoError(t, err)
+
+	sk, err := PrivateKeyFromSeedAndPath(seed, "m/0")
+	require.NoError(t, err)
+	expected, ok := new(big.Int).SetString("20397789859736650942317412262472558107875392172444076792671091975210932703118", 10)
+	require.Equal(t, true, ok)
+	require.DeepEqual(t, expected.FillBytes(make([]byte, 32)), sk)
+
+	_, err = PrivateKeyFromSeedAndPath(seed, "n/0")
+	require.ErrorContains(t, "does not start with m", err)
+	_, err = PrivateKeyFromSeedAndPath(seed, "m/12381//0")
+	require.ErrorContains(t, "no entry at path component 2", err)
+	_, err = PrivateKeyFromSeedAndPath(seed, "m/4294967296")
+	require.ErrorContains(t, "invalid index", err)
+	_, err = PrivateKeyFromSeedAndPath(seed[:31], "m/0")
+	require.ErrorContains(t, "seed must be at least 32 bytes", err)
+}
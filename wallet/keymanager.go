@@ -1,22 +1,22 @@
 package wallet
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/atif-konasl/eth-research/bls"
-	"github.com/atif-konasl/eth-research/bls/herumi"
+	// Register the BLS backends used through the bls package.
+	_ "github.com/atif-konasl/eth-research/bls/backends"
 	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
-	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
-)
-
-var (
-	lock              sync.RWMutex
-	orderedPublicKeys = make([][48]byte, 0)
-	secretKeysCache   = make(map[[48]byte]bls.SecretKey)
 )
 
 const (
@@ -29,18 +29,35 @@ const (
 )
 
 // Defines a struct containing 1-to-1 corresponding
-// private keys and public keys for eth2 validators.
+// private keys and public keys for eth2 validators, along with the
+// unix time each account was added at, zero if unknown.
 type accountStore struct {
-	PrivateKeys [][]byte `json:"private_keys"`
-	PublicKeys  [][]byte `json:"public_keys"`
+	PrivateKeys   [][]byte `json:"private_keys"`
+	PublicKeys    [][]byte `json:"public_keys"`
+	CreationTimes []int64  `json:"creation_times,omitempty"`
 }
 
 
 // Keymanager implementation for imported keystores utilizing EIP-2335.
+// Its account store and key caches are guarded by lock, so a single instance
+// can be used concurrently and several wallets can be opened in one process.
 type Keymanager struct {
 	wallet              *Wallet
+	lock                sync.RWMutex
 	accountsStore       *accountStore
 	disabledPublicKeys  map[[48]byte]bool
+	orderedPublicKeys   [][48]byte
+	secretKeysCache     map[[48]byte]bls.SecretKey
+	accountsChangedFeed *event.Feed
+	slashingProtection  *slashingprotection.Store
+	auditLog            *AuditLog
+	encoding            Encoding
+	// writeLock serializes changes persisted to the accounts keystore file with
+	// reloads from it, so a reload never reads a file older than the caches.
+	writeLock           sync.Mutex
+	watchLock           sync.Mutex
+	watchSubscribers    int
+	stopWatching        context.CancelFunc
 }
 
 
@@ -55,125 +72,329 @@ type AccountsKeystoreRepresentation struct {
 	DisabledPublicKeys []string               `json:"disabled_public_keys"`
 }
 
-// NewKeymanager instantiates a new imported keymanager from configuration options.
-func NewKeymanager(wallet *Wallet) (*Keymanager, error) {
+// Keystore json file representation as a Go struct, following EIP-2335.
+type Keystore struct {
+	Crypto  map[string]interface{} `json:"crypto"`
+	ID      string                 `json:"uuid"`
+	Pubkey  string                 `json:"pubkey"`
+	Version uint                   `json:"version"`
+	Name    string                 `json:"name"`
+	Path    string                 `json:"path"`
+}
+
+// KeymanagerOpts for an imported keymanager.
+type KeymanagerOpts struct {
+	EIPVersion string     `json:"direct_eip_version"`
+	Version    string     `json:"direct_version"`
+	KDFParams  *KDFParams `json:"kdf_params,omitempty"`
+}
+
+// DefaultKeymanagerOpts for an imported keymanager.
+func DefaultKeymanagerOpts() *KeymanagerOpts {
+	return &KeymanagerOpts{
+		EIPVersion: "EIP-2335",
+		Version:    "2",
+	}
+}
+
+// NewKeymanager instantiates the keymanager matching the kind of the wallet.
+func NewKeymanager(ctx context.Context, wallet *Wallet) (IKeymanager, error) {
+	switch wallet.keymanagerKind {
+	case Imported:
+		return NewImportedKeymanager(ctx, wallet)
+	case Derived:
+		return NewDerivedKeymanager(ctx, wallet)
+	case Remote:
+		return NewRemoteKeymanager(ctx, wallet)
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedKeymanagerKind, "kind %s", wallet.keymanagerKind)
+	}
+}
+
+// NewImportedKeymanager instantiates a new imported keymanager from configuration options.
+// It checks signatures against the slashing protection database of the wallet.
+func NewImportedKeymanager(_ context.Context, wallet *Wallet) (*Keymanager, error) {
 	k := &Keymanager{
 		wallet:              wallet,
 		accountsStore:       &accountStore{},
 		disabledPublicKeys:  make(map[[48]byte]bool),
+		orderedPublicKeys:   make([][48]byte, 0),
+		secretKeysCache:     make(map[[48]byte]bls.SecretKey),
+		accountsChangedFeed: new(event.Feed),
 	}
 
 	if err := k.initializeAccountKeystore(); err != nil {
 		return nil, errors.Wrap(err, "failed to initialize account store")
 	}
+	store, err := wallet.SlashingProtection()
+	if err != nil {
+		return nil, err
+	}
+	k.slashingProtection = store
 
 	return k, nil
 }
 
 func  (km *Keymanager) initializeAccountKeystore() error {
+	store, disabledPublicKeys, err := km.readAccountsKeystore()
+	if err != nil {
+		return err
+	}
+	if len(store.PublicKeys) == 0 {
+		return nil
+	}
+	log.WithField("numAccounts", len(store.PublicKeys)).Debug("Read accounts from wallet")
+
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.accountsStore = store
+	km.disabledPublicKeys = disabledPublicKeys
+	if err := km.initializeKeysCachesFromKeystore(); err != nil {
+		return errors.Wrap(err, "failed to initialize keys caches")
+	}
+	return nil
+}
+
+// readAccountsKeystore reads and decrypts the accounts keystore of the wallet. A wallet
+// without accounts keystore yields an empty account store.
+func (km *Keymanager) readAccountsKeystore() (*accountStore, map[[48]byte]bool, error) {
+	km.wallet.passwordLock.RLock()
+	defer km.wallet.passwordLock.RUnlock()
+	disabledPublicKeys := make(map[[48]byte]bool)
 	encoded, err := km.wallet.ReadFileAtPath(AccountsPath, AccountsKeystoreFileName)
 	if err != nil && strings.Contains(err.Error(), "no files found") {
 		// If there are no keys to initialize at all, just exit.
-		return nil
+		return &accountStore{}, disabledPublicKeys, nil
 	} else if err != nil {
-		return errors.Wrapf(err, "could not read keystore file for accounts %s", AccountsKeystoreFileName)
+		return nil, nil, errors.Wrapf(err, "could not read keystore file for accounts %s", AccountsKeystoreFileName)
 	}
 	keystoreFile := &AccountsKeystoreRepresentation{}
 	if err := json.Unmarshal(encoded, keystoreFile); err != nil {
-		return errors.Wrapf(err, "could not decode keystore file for accounts %s", AccountsKeystoreFileName)
+		return nil, nil, errors.Wrapf(err, "could not decode keystore file for accounts %s", AccountsKeystoreFileName)
 	}
+	km.wallet.warnIfWeakerKDF(AccountsKeystoreFileName, keystoreFile.Crypto)
 	// We extract the validator signing private key from the keystore
 	// by utilizing the password and initialize a new BLS secret key from
 	// its raw bytes.
 	password := km.wallet.walletPassword
-	decryptor := keystorev4.New()
-	enc, err := decryptor.Decrypt(keystoreFile.Crypto, password)
-	if err != nil && strings.Contains(err.Error(), "invalid checksum") {
-		return errors.Wrap(err, "wrong password for wallet entered")
+	enc, err := decryptKeystoreCrypto(keystoreFile.Crypto, password)
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, nil, err
 	} else if err != nil {
-		return errors.Wrap(err, "could not decrypt keystore")
+		return nil, nil, errors.Wrap(err, "could not decrypt keystore")
 	}
 
 	store := &accountStore{}
 	if err := json.Unmarshal(enc, store); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if len(store.PublicKeys) != len(store.PrivateKeys) {
-		return errors.New("unequal number of public keys and private keys")
-	}
-	if len(store.PublicKeys) == 0 {
-		return nil
+		return nil, nil, errors.New("unequal number of public keys and private keys")
 	}
-	km.accountsStore = store
-	log.Info("getting public keys from wallet: ", km.accountsStore.PublicKeys)
-
-	lock.Lock()
+	// Accounts stored before creation times were recorded have unknown creation times.
+	creationTimes := make([]int64, len(store.PublicKeys))
+	copy(creationTimes, store.CreationTimes)
+	store.CreationTimes = creationTimes
 	for _, pubKey := range keystoreFile.DisabledPublicKeys {
 		pubKeyBytes, err := hex.DecodeString(pubKey)
 		if err != nil {
-			lock.Unlock()
-			return err
+			return nil, nil, err
 		}
-		km.disabledPublicKeys[bytesutil.ToBytes48(pubKeyBytes)] = true
-	}
-	lock.Unlock()
-	err = km.initializeKeysCachesFromKeystore()
-	if err != nil {
-		return errors.Wrap(err, "failed to initialize keys caches")
+		disabledPublicKeys[bytesutil.ToBytes48(pubKeyBytes)] = true
 	}
-	return err
+	return store, disabledPublicKeys, nil
 }
 
 // Initialize public and secret key caches that are used to speed up the functions
-// FetchValidatingPublicKeys and Sign
+// FetchValidatingPublicKeys and Sign. The caller must hold km.lock for writing.
 func (km *Keymanager) initializeKeysCachesFromKeystore() error {
-	lock.Lock()
-	defer lock.Unlock()
 	count := len(km.accountsStore.PrivateKeys)
-	orderedPublicKeys = make([][48]byte, count)
-	secretKeysCache = make(map[[48]byte]bls.SecretKey, count)
+	km.orderedPublicKeys = make([][48]byte, count)
+	km.secretKeysCache = make(map[[48]byte]bls.SecretKey, count)
 	for i, publicKey := range km.accountsStore.PublicKeys {
 		publicKey48 := bytesutil.ToBytes48(publicKey)
-		orderedPublicKeys[i] = publicKey48
-		secretKey, err := herumi.SecretKeyFromBytes(km.accountsStore.PrivateKeys[i])
+		km.orderedPublicKeys[i] = publicKey48
+		secretKey, err := bls.SecretKeyFromBytes(km.accountsStore.PrivateKeys[i])
 		if err != nil {
 			return errors.Wrap(err, "failed to initialize keys caches from account keystore")
 		}
-		secretKeysCache[publicKey48] = secretKey
+		km.secretKeysCache[publicKey48] = secretKey
 	}
 	return nil
 }
 
 
-// Sign signs a message using a validator key.
-func (km *Keymanager) Sign(slotInfo *SlotInfo, pubKeyIndex uint64) (bls.Signature, error) {
+// FetchValidatingPublicKeys fetches the list of public keys from the imported account keystores,
+// leaving out the ones of disabled accounts.
+func (km *Keymanager) FetchValidatingPublicKeys(_ context.Context) ([][48]byte, error) {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	keys := make([][48]byte, 0, len(km.orderedPublicKeys))
+	for _, pubKey := range km.orderedPublicKeys {
+		if km.disabledPublicKeys[pubKey] {
+			continue
+		}
+		keys = append(keys, pubKey)
+	}
+	return keys, nil
+}
+
+// SubscribeAccountChanges creates an event subscription for a channel
+// to listen for public key changes at runtime, such as when new validator accounts
+// are imported into the keymanager while the validator process is running. While
+// subscribed, the accounts keystore file is watched, so changes written to it by
+// other processes are reloaded and notified as well.
+func (km *Keymanager) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	sub := km.accountsChangedFeed.Subscribe(pubKeysChan)
+	km.startWatchingAccounts()
+	return &accountChangesSubscription{
+		Subscription: sub,
+		release:      km.stopWatchingAccounts,
+	}
+}
+
+// UseSlashingProtection makes the keymanager record every signed slot in the given
+// store, instead of the one of the wallet, and refuse to sign a conflicting slot info
+// for the same slot. A nil store disables slashing protection.
+func (km *Keymanager) UseSlashingProtection(store *slashingprotection.Store) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.slashingProtection = store
+}
+
+// UseAuditLog makes the keymanager record every signing request and its result in the
+// given audit log. Signatures which cannot be recorded are not handed out.
+func (km *Keymanager) UseAuditLog(auditLog *AuditLog) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.auditLog = auditLog
+}
 
-	slotInfoHash := slotInfo.Hash()
-	publicKey := km.accountsStore.PublicKeys[pubKeyIndex]
-	if publicKey == nil {
-		return nil, errors.New("nil public key in request")
+// SetEncoding selects the encoding of slot infos from which signing roots are
+// computed. RLPEncoding is used by default.
+func (km *Keymanager) SetEncoding(encoding Encoding) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.encoding = encoding
+}
+
+// Sign signs the signing root of the slot info in the domain using the validator key
+// of the given public key. Returns ErrUnknownPublicKey if the key is not in the wallet
+// and ErrDisabledPublicKey if its account is disabled.
+func (km *Keymanager) Sign(slotInfo *SlotInfo, domain Domain, pubKey [48]byte) (bls.Signature, error) {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeysCache[pubKey]
+	disabled := km.disabledPublicKeys[pubKey]
+	protection := km.slashingProtection
+	auditLog := km.auditLog
+	encoding := km.encoding
+	km.lock.RUnlock()
+	var signingRoot []byte
+	signature, err := func() (bls.Signature, error) {
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+		}
+		if disabled {
+			return nil, errors.Wrapf(ErrDisabledPublicKey, "%#x", pubKey)
+		}
+		root, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+		if err != nil {
+			return nil, err
+		}
+		signingRoot = root[:]
+		if err := checkSlashingProtection(protection, slotInfo.Slot, root, pubKey); err != nil {
+			return nil, err
+		}
+		return secretKey.Sign(root[:]), nil
+	}()
+	if auditErr := recordSigning(auditLog, pubKey, slotInfo, signingRoot, signature, err); auditErr != nil {
+		return nil, auditErr
 	}
-	lock.RLock()
-	secretKey, ok := secretKeysCache[bytesutil.ToBytes48(publicKey)]
-	lock.RUnlock()
+	return signature, err
+}
+
+// VerifySignature verifies a signature over the slot info in the domain given the
+// public key of one of the wallet's accounts.
+func (km *Keymanager) VerifySignature(slotInfo *SlotInfo, domain Domain, pubKey [48]byte, signature bls.Signature) error {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeysCache[pubKey]
+	encoding := km.encoding
+	km.lock.RUnlock()
 	if !ok {
-		return nil, errors.New("no signing key found in keys cache")
+		return errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	signingRoot, err := ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(secretKey.PublicKey(), signingRoot[:]) {
+		return ErrSigFailedToVerify
 	}
-	return secretKey.Sign(slotInfoHash.Bytes()), nil
+	return nil
 }
 
-// VerifyBlockSigningRoot verifies the signing root of a block given it's public key, signature and domain.
-func (km *Keymanager) VerifySignature(slotInfo *SlotInfo, pubKeyIndex uint64, signature bls.Signature) error {
-	slotInfoHash := slotInfo.Hash()
-	pub := km.accountsStore.PublicKeys[pubKeyIndex]
-
-	publicKey, err := herumi.PublicKeyFromBytes(pub)
+// newAccountsKeystore encrypts the given account store with the wallet password and
+// KDF parameters into the on-disk accounts keystore representation.
+func newAccountsKeystore(
+	store *accountStore,
+	password string,
+	disabledPublicKeys map[[48]byte]bool,
+	kdfParams *KDFParams,
+) (*AccountsKeystoreRepresentation, error) {
+	if len(store.PrivateKeys) != len(store.PublicKeys) {
+		return nil, fmt.Errorf(
+			"number of private keys and public keys is not equal: %d != %d", len(store.PrivateKeys), len(store.PublicKeys),
+		)
+	}
+	encryptor := newKeystoreEncryptor(kdfParams)
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	encodedStore, err := json.MarshalIndent(store, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	cryptoFields, err := encryptor.Encrypt(encodedStore, password)
 	if err != nil {
-		return errors.Wrap(err, "could not convert bytes to public key")
+		return nil, errors.Wrap(err, "could not encrypt accounts")
+	}
+	disabledPubKeys := make([]string, 0, len(disabledPublicKeys))
+	for pubKey := range disabledPublicKeys {
+		disabledPubKeys = append(disabledPubKeys, fmt.Sprintf("%x", pubKey))
+	}
+	sort.Strings(disabledPubKeys)
+	return &AccountsKeystoreRepresentation{
+		Crypto:             cryptoFields,
+		ID:                 id.String(),
+		Version:            encryptor.Version(),
+		Name:               encryptor.Name(),
+		DisabledPublicKeys: disabledPubKeys,
+	}, nil
+}
+
+// recordSigning records a signing request and its result in the audit log. A nil audit
+// log disables recording.
+func recordSigning(
+	auditLog *AuditLog, pubKey [48]byte, slotInfo *SlotInfo, signingRoot []byte, signature bls.Signature, signErr error,
+) error {
+	if auditLog == nil {
+		return nil
 	}
+	if err := auditLog.recordSigning(pubKey, slotInfo, signingRoot, signature, signErr); err != nil {
+		return errors.Wrap(err, "could not record signing request in audit log")
+	}
+	return nil
+}
 
-	if !signature.Verify(publicKey, slotInfoHash[:]) {
-		return ErrSigFailedToVerify
+// checkSlashingProtection records the signing root as signed by the public key at the
+// slot, unless it conflicts with an earlier signature. A nil store disables the check.
+func checkSlashingProtection(store *slashingprotection.Store, slot uint64, signingRoot [32]byte, pubKey [48]byte) error {
+	if store == nil {
+		return nil
+	}
+	if err := store.CheckAndRecordSlotSignature(pubKey, slot, signingRoot); err != nil {
+		return errors.Wrap(err, "refusing to sign slot info")
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -1,8 +1,16 @@
 package wallet
 
 import (
-	"github.com/atif-konasl/eth-research/testutil/require"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
 	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
 )
 
 func Test_InitializeAccountKeystore(t *testing.T) {
@@ -10,12 +18,223 @@ func Test_InitializeAccountKeystore(t *testing.T) {
 		WalletDir: "./prysm-wallet-v2",
 		KeymanagerKind: Kind(0),
 		WalletPassword: "Konasl@123",
+		// Leave the fixture wallet unchanged.
+		DisableSlashingProtection: true,
 	}
 
 	wallet, err := OpenWallet(nil, &config)
 	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, wallet.Close())
+	}()
 
-	keyManager, err := NewKeymanager(wallet)
+	keyManager, err := NewImportedKeymanager(context.Background(), wallet)
 	require.NoError(t, err)
 	require.Equal(t, 2, len(keyManager.accountsStore.PublicKeys))
 }
+
+func TestNewKeymanager_DispatchesOnKind(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	_, ok := km.(*Keymanager)
+	require.Equal(t, true, ok, "Expected an imported keymanager")
+
+	derivedWallet := setupDerivedWallet(t)
+	_, err = RecoverFromMnemonic(ctx, derivedWallet, testMnemonic, "", 1, false /* overwrite */)
+	require.NoError(t, err)
+	km, err = NewKeymanager(ctx, derivedWallet)
+	require.NoError(t, err)
+	_, ok = km.(*DerivedKeymanager)
+	require.Equal(t, true, ok, "Expected a derived keymanager")
+
+	_, err = NewKeymanager(ctx, &Wallet{keymanagerKind: Kind(7)})
+	require.ErrorContains(t, "unsupported keymanager kind", err)
+}
+
+func TestKeymanager_SubscribeAccountChanges(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	pubKeysChan := make(chan [][48]byte, 1)
+	sub := km.SubscribeAccountChanges(pubKeysChan)
+	defer sub.Unsubscribe()
+
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKeys := <-pubKeysChan
+	require.Equal(t, 1, len(pubKeys))
+	require.DeepEqual(t, secretKey.PublicKey().Marshal(), pubKeys[0][:])
+
+	fetched, err := km.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, pubKeys, fetched)
+}
+
+func TestKeymanager_SignByPublicKey(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore1, secretKey1 := createRandomKeystore(t, "password")
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1, keystore2}, []string{"password", "password"}))
+	pubKey1 := bytesutil.ToBytes48(secretKey1.PublicKey().Marshal())
+	pubKey2 := bytesutil.ToBytes48(secretKey2.PublicKey().Marshal())
+
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	signature, err := km.Sign(slotInfo, testDomain, pubKey2)
+	require.NoError(t, err)
+	signingRoot := ComputeSigningRoot(slotInfo, testDomain)
+	require.DeepEqual(t, secretKey2.Sign(signingRoot[:]).Marshal(), signature.Marshal())
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKey2, signature))
+	require.Equal(t, ErrSigFailedToVerify, km.VerifySignature(slotInfo, testDomain, pubKey1, signature))
+
+	unknownPubKey := [48]byte{1, 2, 3}
+	_, err = km.Sign(slotInfo, testDomain, unknownPubKey)
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	require.Equal(t, true, errors.Is(km.VerifySignature(slotInfo, testDomain, unknownPubKey, signature), ErrUnknownPublicKey))
+
+	km.disabledPublicKeys[pubKey1] = true
+	_, err = km.Sign(slotInfo, testDomain, pubKey1)
+	require.Equal(t, true, errors.Is(err, ErrDisabledPublicKey))
+}
+
+func TestKeymanager_SlashingProtection(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	store, err := slashingprotection.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+	km.UseSlashingProtection(store)
+
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+	_, err = km.Sign(NewSlotInfo(2, 65, 5455), testDomain, pubKey)
+	require.NoError(t, err)
+}
+
+func TestKeymanager_DefaultSlashingProtection(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+
+	// The signed slots are kept in the wallet directory, across openings of the wallet.
+	require.NoError(t, w.Close())
+	w, err = OpenWallet(ctx, &Config{WalletDir: w.walletDir, WalletPassword: testWalletPassword})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+	km, err = NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, slashingprotection.ErrDoubleProposal))
+	require.Equal(t, true, fileutil.FileExists(
+		filepath.Join(w.walletDir, SlashingProtectionDirName, slashingprotection.ProtectionDbFileName),
+	))
+}
+
+func TestKeymanager_DisableSlashingProtection(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	require.NoError(t, w.Close())
+	w, err := OpenWallet(ctx, &Config{
+		WalletDir:                 w.walletDir,
+		WalletPassword:            testWalletPassword,
+		DisableSlashingProtection: true,
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+	_, err = km.Sign(NewSlotInfo(2, 64, 5455), testDomain, pubKey)
+	require.NoError(t, err)
+	hasDir, err := fileutil.HasDir(filepath.Join(w.walletDir, SlashingProtectionDirName))
+	require.NoError(t, err)
+	require.Equal(t, false, hasDir)
+}
+
+func TestKeymanager_MultipleWalletsInParallel(t *testing.T) {
+	ctx := context.Background()
+	const numWallets = 4
+	wallets := make([]*Wallet, numWallets)
+	pubKeys := make([][48]byte, numWallets)
+	for i := 0; i < numWallets; i++ {
+		wallets[i] = setupImportedWallet(t)
+		km, err := NewImportedKeymanager(ctx, wallets[i])
+		require.NoError(t, err)
+		keystore, secretKey := createRandomKeystore(t, "password")
+		require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+		pubKeys[i] = bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	}
+
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	keymanagers := make([]*Keymanager, numWallets)
+	var wg sync.WaitGroup
+	errs := make(chan error, numWallets)
+	for i := 0; i < numWallets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			km, err := NewImportedKeymanager(ctx, wallets[i])
+			if err != nil {
+				errs <- err
+				return
+			}
+			keymanagers[i] = km
+			for j := 0; j < 10; j++ {
+				signature, err := km.Sign(slotInfo, testDomain, pubKeys[i])
+				if err != nil {
+					errs <- err
+					return
+				}
+				if err := km.VerifySignature(slotInfo, testDomain, pubKeys[i], signature); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Every keymanager only knows about the keys of its own wallet.
+	for i, km := range keymanagers {
+		validating, err := km.FetchValidatingPublicKeys(ctx)
+		require.NoError(t, err)
+		require.DeepEqual(t, [][48]byte{pubKeys[i]}, validating)
+		_, err = km.Sign(slotInfo, testDomain, pubKeys[(i+1)%numWallets])
+		require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+	}
+}
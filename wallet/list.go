@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"context"
+	"time"
+)
+
+// Account of a wallet as listed by ListAccounts.
+type Account struct {
+	PublicKey [48]byte
+	// Index of the account in the accounts keystore.
+	Index int
+	// Name is a human-readable petname derived from the public key.
+	Name     string
+	Disabled bool
+	// CreatedAt is the time the account was added to the wallet, zero for accounts
+	// added before creation times were recorded.
+	CreatedAt time.Time
+}
+
+// ListAccounts lists every account of the imported keymanager, disabled ones included,
+// in the order they are stored in the accounts keystore.
+func (km *Keymanager) ListAccounts(_ context.Context) ([]*Account, error) {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	accounts := make([]*Account, len(km.orderedPublicKeys))
+	for i, pubKey := range km.orderedPublicKeys {
+		account := &Account{
+			PublicKey: pubKey,
+			Index:     i,
+			Name:      petname(pubKey),
+			Disabled:  km.disabledPublicKeys[pubKey],
+		}
+		if i < len(km.accountsStore.CreationTimes) && km.accountsStore.CreationTimes[i] != 0 {
+			account.CreatedAt = time.Unix(km.accountsStore.CreationTimes[i], 0)
+		}
+		accounts[i] = account
+	}
+	return accounts, nil
+}
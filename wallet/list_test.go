@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func TestKeymanager_ListAccounts(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	before := time.Now().Add(-time.Second)
+	keystore1, secretKey1 := createRandomKeystore(t, "password")
+	keystore2, secretKey2 := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore1, keystore2}, []string{"password", "password"}))
+	pubKey1 := bytesutil.ToBytes48(secretKey1.PublicKey().Marshal())
+	pubKey2 := bytesutil.ToBytes48(secretKey2.PublicKey().Marshal())
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{pubKey2}))
+
+	accounts, err := km.ListAccounts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(accounts))
+	require.Equal(t, pubKey1, accounts[0].PublicKey)
+	require.Equal(t, 0, accounts[0].Index)
+	require.Equal(t, false, accounts[0].Disabled)
+	require.Equal(t, pubKey2, accounts[1].PublicKey)
+	require.Equal(t, 1, accounts[1].Index)
+	require.Equal(t, true, accounts[1].Disabled)
+	for _, account := range accounts {
+		require.Equal(t, petname(account.PublicKey), account.Name)
+		require.Equal(t, true, account.CreatedAt.After(before), "Unexpected creation time %v", account.CreatedAt)
+	}
+
+	// Metadata survives reopening the wallet.
+	reloaded, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	reloadedAccounts, err := reloaded.ListAccounts(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, accounts, reloadedAccounts)
+}
+
+func TestKeymanager_ListAccounts_UnknownCreationTime(t *testing.T) {
+	config := &Config{WalletDir: "./prysm-wallet-v2", WalletPassword: "Konasl@123", DisableSlashingProtection: true}
+	km, err := NewImportedKeymanager(context.Background(), NewWallet(config))
+	require.NoError(t, err)
+	accounts, err := km.ListAccounts(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(accounts))
+	for _, account := range accounts {
+		require.Equal(t, true, account.CreatedAt.IsZero())
+	}
+}
+
+func TestPetname(t *testing.T) {
+	pubKey := [48]byte{1, 2, 3}
+	require.Equal(t, petname(pubKey), petname(pubKey))
+	require.Equal(t, 3, len(strings.Split(petname(pubKey), "-")))
+	require.NotEqual(t, petname(pubKey), petname([48]byte{3, 2, 1}))
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WalletLockFileName of the lock file held in the wallet directory by the process
+// which opened the wallet. It contains the PID of that process.
+const WalletLockFileName = "wallet.lock"
+
+// walletLockHeldError names the process holding the lock file at a path, as far as
+// it can be read from the file.
+func walletLockHeldError(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(ErrWalletLocked, "held by another process")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return errors.Wrap(ErrWalletLocked, "held by another process")
+	}
+	return errors.Wrapf(ErrWalletLocked, "held by process %d, lock file %s", pid, path)
+}
+
+// writeLockHolder records the current process as holder of an acquired lock file.
+func writeLockHolder(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
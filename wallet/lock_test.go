@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func TestOpenWallet_Lock(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	lockPath := filepath.Join(w.walletDir, WalletLockFileName)
+	require.Equal(t, true, fileutil.FileExists(lockPath))
+
+	config := &Config{WalletDir: w.walletDir, WalletPassword: testWalletPassword}
+	_, err := OpenWallet(ctx, config)
+	require.Equal(t, true, errors.Is(err, ErrWalletLocked))
+	require.ErrorContains(t, fmt.Sprintf("held by process %d", os.Getpid()), err)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	require.Equal(t, false, fileutil.FileExists(lockPath))
+
+	reopened, err := OpenWallet(ctx, config)
+	require.NoError(t, err)
+	require.Equal(t, true, fileutil.FileExists(lockPath))
+	require.NoError(t, reopened.Close())
+}
+
+func TestOpenWallet_StaleLockFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Lock files are not advisory locks on windows")
+	}
+	w := setupImportedWallet(t)
+	require.NoError(t, w.Close())
+
+	// A lock file left behind by a crashed process is not locked anymore.
+	lockPath := filepath.Join(w.walletDir, WalletLockFileName)
+	require.NoError(t, fileutil.WriteFile(lockPath, []byte("999999\n")))
+	reopened, err := OpenWallet(context.Background(), &Config{WalletDir: w.walletDir, WalletPassword: testWalletPassword})
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+}
@@ -0,0 +1,57 @@
+// +build !windows
+
+package wallet
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// acquireLockFile takes an exclusive advisory lock on the file at path, creating it
+// if needed, without blocking.
+func acquireLockFile(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not open lock file %s", path)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			_ = f.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, walletLockHeldError(path)
+			}
+			return nil, errors.Wrapf(err, "could not lock %s", path)
+		}
+		// The previous holder removes the file when releasing the lock, so the file
+		// locked may no longer be the one at path. Retry on the new file in that case.
+		locked, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(locked, current) {
+			if err := writeLockHolder(f); err != nil {
+				_ = f.Close()
+				return nil, errors.Wrapf(err, "could not write lock file %s", path)
+			}
+			return f, nil
+		}
+		_ = f.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// releaseLockFile removes the lock file and releases its lock.
+func releaseLockFile(f *os.File) error {
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		_ = f.Close()
+		return err
+	}
+	// Closing the file releases the lock.
+	return f.Close()
+}
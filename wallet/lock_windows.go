@@ -0,0 +1,35 @@
+// +build windows
+
+package wallet
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// acquireLockFile creates the lock file at path, failing if it already exists. Unlike
+// on other platforms the lock is not released by the OS when the process dies, so the
+// file left behind by a crashed process has to be removed by hand.
+func acquireLockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil, walletLockHeldError(path)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "could not create lock file %s", path)
+	}
+	if err := writeLockHolder(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return nil, errors.Wrapf(err, "could not write lock file %s", path)
+	}
+	return f, nil
+}
+
+// releaseLockFile closes and removes the lock file.
+func releaseLockFile(f *os.File) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,97 @@
+// Package mock provides an in-memory implementation of the wallet keymanager
+// interface, for tests of consumers which should not depend on files on disk.
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/wallet"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/pkg/errors"
+)
+
+var _ wallet.IKeymanager = (*Keymanager)(nil)
+
+// Keymanager is an in-memory keymanager which signs with the secret keys it was given.
+type Keymanager struct {
+	lock                sync.RWMutex
+	orderedPublicKeys   [][48]byte
+	secretKeys          map[[48]byte]bls.SecretKey
+	accountsChangedFeed *event.Feed
+}
+
+// NewKeymanager creates a mock keymanager holding the given secret keys.
+func NewKeymanager(secretKeys []bls.SecretKey) *Keymanager {
+	km := &Keymanager{
+		secretKeys:          make(map[[48]byte]bls.SecretKey, len(secretKeys)),
+		accountsChangedFeed: new(event.Feed),
+	}
+	km.addSecretKeys(secretKeys)
+	return km
+}
+
+// AddAccounts adds secret keys to the keymanager and notifies subscribers of the new
+// list of public keys.
+func (km *Keymanager) AddAccounts(secretKeys []bls.SecretKey) {
+	km.lock.Lock()
+	km.addSecretKeys(secretKeys)
+	pubKeys := make([][48]byte, len(km.orderedPublicKeys))
+	copy(pubKeys, km.orderedPublicKeys)
+	km.lock.Unlock()
+	km.accountsChangedFeed.Send(pubKeys)
+}
+
+// FetchValidatingPublicKeys returns the public keys of the held secret keys.
+func (km *Keymanager) FetchValidatingPublicKeys(_ context.Context) ([][48]byte, error) {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	pubKeys := make([][48]byte, len(km.orderedPublicKeys))
+	copy(pubKeys, km.orderedPublicKeys)
+	return pubKeys, nil
+}
+
+// Sign signs the slot info in the domain with the secret key of the given public key.
+func (km *Keymanager) Sign(slotInfo *wallet.SlotInfo, domain wallet.Domain, pubKey [48]byte) (bls.Signature, error) {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeys[pubKey]
+	km.lock.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(wallet.ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	signingRoot := wallet.ComputeSigningRoot(slotInfo, domain)
+	return secretKey.Sign(signingRoot[:]), nil
+}
+
+// VerifySignature verifies the signature with the given public key.
+func (km *Keymanager) VerifySignature(slotInfo *wallet.SlotInfo, domain wallet.Domain, pubKey [48]byte, signature bls.Signature) error {
+	km.lock.RLock()
+	secretKey, ok := km.secretKeys[pubKey]
+	km.lock.RUnlock()
+	if !ok {
+		return errors.Wrapf(wallet.ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	signingRoot := wallet.ComputeSigningRoot(slotInfo, domain)
+	if !signature.Verify(secretKey.PublicKey(), signingRoot[:]) {
+		return wallet.ErrSigFailedToVerify
+	}
+	return nil
+}
+
+// SubscribeAccountChanges subscribes to the account changes made through AddAccounts.
+func (km *Keymanager) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	return km.accountsChangedFeed.Subscribe(pubKeysChan)
+}
+
+func (km *Keymanager) addSecretKeys(secretKeys []bls.SecretKey) {
+	for _, secretKey := range secretKeys {
+		pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+		if _, ok := km.secretKeys[pubKey]; ok {
+			continue
+		}
+		km.orderedPublicKeys = append(km.orderedPublicKeys, pubKey)
+		km.secretKeys[pubKey] = secretKey
+	}
+}
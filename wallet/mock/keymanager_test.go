@@ -0,0 +1,136 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/atif-konasl/eth-research/wallet"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+var testDomain = wallet.ComputeDomain(wallet.DomainSlotInfo, [4]byte{}, [32]byte{})
+
+func randomSecretKeys(t *testing.T, n int) []bls.SecretKey {
+	secretKeys := make([]bls.SecretKey, n)
+	for i := range secretKeys {
+		secretKey, err := bls.RandKey()
+		require.NoError(t, err)
+		secretKeys[i] = secretKey
+	}
+	return secretKeys
+}
+
+func TestKeymanager_SignAndVerify(t *testing.T) {
+	secretKeys := randomSecretKeys(t, 2)
+	km := NewKeymanager(secretKeys)
+	pubKey0 := bytesutil.ToBytes48(secretKeys[0].PublicKey().Marshal())
+	pubKey1 := bytesutil.ToBytes48(secretKeys[1].PublicKey().Marshal())
+	slotInfo := wallet.NewSlotInfo(2, 64, 5454)
+
+	signature, err := km.Sign(slotInfo, testDomain, pubKey0)
+	require.NoError(t, err)
+	root := wallet.ComputeSigningRoot(slotInfo, testDomain)
+	require.DeepEqual(t, secretKeys[0].Sign(root[:]).Marshal(), signature.Marshal())
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKey0, signature))
+	require.Equal(t, wallet.ErrSigFailedToVerify, km.VerifySignature(slotInfo, testDomain, pubKey1, signature))
+	require.Equal(
+		t, wallet.ErrSigFailedToVerify, km.VerifySignature(wallet.NewSlotInfo(2, 64, 5455), testDomain, pubKey0, signature),
+	)
+}
+
+func TestKeymanager_UnknownPublicKey(t *testing.T) {
+	km := NewKeymanager(randomSecretKeys(t, 1))
+	unknown := randomSecretKeys(t, 1)[0]
+	pubKey := bytesutil.ToBytes48(unknown.PublicKey().Marshal())
+	slotInfo := wallet.NewSlotInfo(2, 64, 5454)
+
+	_, err := km.Sign(slotInfo, testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, wallet.ErrUnknownPublicKey))
+	root := wallet.ComputeSigningRoot(slotInfo, testDomain)
+	err = km.VerifySignature(slotInfo, testDomain, pubKey, unknown.Sign(root[:]))
+	require.Equal(t, true, errors.Is(err, wallet.ErrUnknownPublicKey))
+}
+
+// The mock signs and verifies as the derived keymanager holding the same keys.
+func TestKeymanager_MatchesDerivedKeymanager(t *testing.T) {
+	ctx := context.Background()
+	w, err := wallet.CreateWallet(ctx, &wallet.Config{
+		WalletDir:      filepath.Join(t.TempDir(), "wallet"),
+		KeymanagerKind: wallet.Derived,
+		WalletPassword: "Passwordz0320$",
+		KDFParams:      wallet.FastKDFParams(),
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+	derived, err := wallet.RecoverFromMnemonic(ctx, w, testMnemonic, "", 2, false /* overwrite */)
+	require.NoError(t, err)
+	seed := bip39.NewSeed(testMnemonic, "")
+	secretKeys := make([]bls.SecretKey, 2)
+	for i := range secretKeys {
+		privKey, err := wallet.PrivateKeyFromSeedAndPath(seed, fmt.Sprintf(wallet.ValidatingKeyDerivationPathTemplate, i))
+		require.NoError(t, err)
+		secretKeys[i], err = bls.SecretKeyFromBytes(privKey)
+		require.NoError(t, err)
+	}
+	km := NewKeymanager(secretKeys)
+
+	pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	derivedPubKeys, err := derived.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.DeepEqual(t, derivedPubKeys, pubKeys)
+
+	slotInfo := wallet.NewSlotInfo(2, 64, 5454)
+	for _, pubKey := range pubKeys {
+		signature, err := km.Sign(slotInfo, testDomain, pubKey)
+		require.NoError(t, err)
+		derivedSignature, err := derived.Sign(slotInfo, testDomain, pubKey)
+		require.NoError(t, err)
+		require.DeepEqual(t, derivedSignature.Marshal(), signature.Marshal())
+		require.NoError(t, derived.VerifySignature(slotInfo, testDomain, pubKey, signature))
+		require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKey, derivedSignature))
+	}
+}
+
+func TestKeymanager_SubscribeAccountChanges(t *testing.T) {
+	secretKeys := randomSecretKeys(t, 2)
+	km := NewKeymanager(secretKeys[:1])
+	pubKeysChan := make(chan [][48]byte, 1)
+	sub := km.SubscribeAccountChanges(pubKeysChan)
+	defer sub.Unsubscribe()
+
+	// Keys already held are not added twice.
+	km.AddAccounts(secretKeys)
+	expected := [][48]byte{
+		bytesutil.ToBytes48(secretKeys[0].PublicKey().Marshal()),
+		bytesutil.ToBytes48(secretKeys[1].PublicKey().Marshal()),
+	}
+	select {
+	case pubKeys := <-pubKeysChan:
+		require.DeepEqual(t, expected, pubKeys)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Account changes were not notified")
+	}
+	pubKeys, err := km.FetchValidatingPublicKeys(context.Background())
+	require.NoError(t, err)
+	require.DeepEqual(t, expected, pubKeys)
+
+	sub.Unsubscribe()
+	km.AddAccounts(randomSecretKeys(t, 1))
+	select {
+	case <-pubKeysChan:
+		t.Fatal("Account changes were notified after unsubscribing")
+	default:
+	}
+}
@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Suffix of the copy kept of a keystore while it is re-encrypted under a new password.
+const passwordChangeBackupSuffix = ".backup"
+
+// ChangePassword re-encrypts the secrets of the wallet, the accounts keystore of an
+// imported wallet or the seed of a derived one, under a new password. The keystore is
+// backed up first and replaced atomically, and the backup is only deleted once the new
+// keystore has been read back and decrypted with the new password. Keymanagers opened
+// from the wallet wait for the change to complete and write their keystores with the
+// new password from then on.
+func (w *Wallet) ChangePassword(ctx context.Context, oldPassword, newPassword string) error {
+	var filePath, fileName string
+	switch w.keymanagerKind {
+	case Imported:
+		filePath, fileName = AccountsPath, AccountsKeystoreFileName
+	case Derived:
+		filePath, fileName = "", EncryptedSeedFileName
+	default:
+		return fmt.Errorf("%s wallets have no password protected secrets", w.keymanagerKind)
+	}
+	if newPassword == "" {
+		return errors.New("new wallet password cannot be empty")
+	}
+	w.passwordLock.Lock()
+	defer w.passwordLock.Unlock()
+
+	encoded, err := w.ReadFileAtPath(filePath, fileName)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", fileName)
+	}
+	secret, err := decryptWalletKeystore(encoded, oldPassword)
+	if err != nil {
+		return err
+	}
+	reencrypted, err := encryptWalletKeystore(encoded, secret, newPassword, w.kdfParams)
+	if err != nil {
+		return err
+	}
+
+	backupFileName := fileName + passwordChangeBackupSuffix
+	if err := w.WriteFileAtPath(ctx, filePath, backupFileName, encoded); err != nil {
+		return errors.Wrap(err, "could not back up keystore")
+	}
+	if err := w.WriteFileAtPath(ctx, filePath, fileName, reencrypted); err != nil {
+		return errors.Wrap(err, "could not write re-encrypted keystore")
+	}
+	if err := w.verifyKeystore(filePath, fileName, secret, newPassword); err != nil {
+		if restoreErr := w.WriteFileAtPath(ctx, filePath, fileName, encoded); restoreErr != nil {
+			log.WithError(restoreErr).Errorf(
+				"Could not restore keystore, a copy is kept in %s",
+				filepath.Join(w.accountsPath, filePath, backupFileName),
+			)
+			return errors.Wrap(err, "could not verify re-encrypted keystore")
+		}
+		return errors.Wrap(err, "could not verify re-encrypted keystore, old keystore restored")
+	}
+	if err := os.Remove(filepath.Join(w.accountsPath, filePath, backupFileName)); err != nil {
+		log.WithError(err).Warn("Could not remove keystore backup")
+	}
+	w.walletPassword = newPassword
+	log.WithField("keymanagerKind", w.keymanagerKind.String()).Info("Changed wallet password")
+	return nil
+}
+
+// verifyKeystore reads a keystore back from disk and checks that it decrypts to the
+// expected secret with the given password.
+func (w *Wallet) verifyKeystore(filePath, fileName string, secret []byte, password string) error {
+	encoded, err := w.ReadFileAtPath(filePath, fileName)
+	if err != nil {
+		return err
+	}
+	decrypted, err := decryptWalletKeystore(encoded, password)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(decrypted, secret) {
+		return errors.New("keystore does not decrypt to the original secret")
+	}
+	return nil
+}
+
+// decryptWalletKeystore decrypts the crypto field of an encoded EIP-2335 style keystore.
+func decryptWalletKeystore(encoded []byte, password string) ([]byte, error) {
+	keystore := &struct {
+		Crypto map[string]interface{} `json:"crypto"`
+	}{}
+	if err := json.Unmarshal(encoded, keystore); err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore")
+	}
+	secret, err := decryptKeystoreCrypto(keystore.Crypto, password)
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not decrypt keystore")
+	}
+	return secret, nil
+}
+
+// encryptWalletKeystore replaces the crypto field of an encoded keystore by the secret
+// encrypted with the password and KDF parameters, leaving its other fields untouched.
+func encryptWalletKeystore(encoded, secret []byte, password string, kdfParams *KDFParams) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, errors.Wrap(err, "could not decode keystore")
+	}
+	encryptor := newKeystoreEncryptor(kdfParams)
+	cryptoFields, err := encryptor.Encrypt(secret, password)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encrypt keystore")
+	}
+	encodedCrypto, err := json.Marshal(cryptoFields)
+	if err != nil {
+		return nil, err
+	}
+	fields["crypto"] = encodedCrypto
+	return json.MarshalIndent(fields, "", "\t")
+}
@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func TestWallet_ChangePassword_Imported(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	require.NoError(t, km.DisableAccounts(ctx, [][48]byte{bytesutil.ToBytes48(secretKey.PublicKey().Marshal())}))
+
+	err = w.ChangePassword(ctx, "wrong password", "new password")
+	require.ErrorContains(t, "wrong password for wallet entered", err)
+
+	require.NoError(t, w.ChangePassword(ctx, testWalletPassword, "new password"))
+	backupPath := filepath.Join(w.accountsPath, AccountsPath, AccountsKeystoreFileName+passwordChangeBackupSuffix)
+	require.Equal(t, false, fileutil.FileExists(backupPath))
+	require.NoError(t, w.Close())
+
+	reopened, err := OpenWallet(ctx, &Config{WalletDir: w.walletDir, WalletPassword: "new password"})
+	require.NoError(t, err)
+	reloaded, err := NewImportedKeymanager(ctx, reopened)
+	require.NoError(t, err)
+	require.DeepEqual(t, km.orderedPublicKeys, reloaded.orderedPublicKeys)
+	require.DeepEqual(t, km.disabledPublicKeys, reloaded.disabledPublicKeys)
+	require.NoError(t, reopened.Close())
+
+	reopened, err = OpenWallet(ctx, &Config{WalletDir: w.walletDir, WalletPassword: testWalletPassword})
+	require.NoError(t, err)
+	_, err = NewImportedKeymanager(ctx, reopened)
+	require.ErrorContains(t, "wrong password for wallet entered", err)
+	require.NoError(t, reopened.Close())
+
+	// Keystores written after the change use the new password.
+	keystore2, _ := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore2}, []string{"password"}))
+	reopened, err = OpenWallet(ctx, &Config{WalletDir: w.walletDir, WalletPassword: "new password"})
+	require.NoError(t, err)
+	reloaded, err = NewImportedKeymanager(ctx, reopened)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(reloaded.orderedPublicKeys))
+	require.NoError(t, reopened.Close())
+}
+
+func TestWallet_ChangePassword_Derived(t *testing.T) {
+	ctx := context.Background()
+	w := setupDerivedWallet(t)
+	km, err := RecoverFromMnemonic(ctx, w, testMnemonic, "", 2, false /* overwrite */)
+	require.NoError(t, err)
+
+	require.NoError(t, w.ChangePassword(ctx, testWalletPassword, "new password"))
+	// Accounts created by a keymanager opened before the change keep the seed
+	// encrypted with the new password.
+	_, err = km.CreateAccount(ctx)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	reopened, err := OpenWallet(ctx, &Config{WalletDir: w.walletDir, WalletPassword: "new password"})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, reopened.Close())
+	}()
+	reloaded, err := NewDerivedKeymanager(ctx, reopened)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(reloaded.orderedPublicKeys))
+	require.DeepEqual(t, km.orderedPublicKeys, reloaded.orderedPublicKeys)
+	require.Equal(t, km.seedCfg.NextAccount, reloaded.seedCfg.NextAccount)
+	require.Equal(t, km.seedCfg.ID, reloaded.seedCfg.ID)
+}
+
+func TestWallet_ChangePassword_Remote(t *testing.T) {
+	w := NewWallet(&Config{KeymanagerKind: Remote})
+	err := w.ChangePassword(context.Background(), "old", "new")
+	require.ErrorContains(t, "remote wallets have no password protected secrets", err)
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// WalletPasswordEnvVar is the environment variable read by DefaultPasswordSource.
+const WalletPasswordEnvVar = "WALLET_PASSWORD"
+
+// PasswordSource supplies the password unlocking a wallet. Sources which have no
+// password to offer, such as an unset environment variable, return ErrPasswordUnavailable.
+type PasswordSource interface {
+	WalletPassword() (string, error)
+}
+
+// PasswordFile reads the wallet password from the file at its path. The file must
+// have 0600 permissions and a trailing newline is ignored.
+type PasswordFile string
+
+// WalletPassword reads the password file.
+func (f PasswordFile) WalletPassword() (string, error) {
+	path := string(f)
+	if !fileutil.FileExists(path) {
+		return "", errors.Wrapf(ErrPasswordUnavailable, "no password file at %s", path)
+	}
+	ok, err := fileutil.HasReadWritePermissions(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not check permissions of password file %s", path)
+	}
+	if !ok {
+		return "", fmt.Errorf("password file %s must have 0600 permissions", path)
+	}
+	data, err := fileutil.ReadFileAsBytes(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read password file %s", path)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
+
+// EnvPassword reads the wallet password from the environment variable it names.
+type EnvPassword string
+
+// WalletPassword reads the environment variable.
+func (e EnvPassword) WalletPassword() (string, error) {
+	password, ok := os.LookupEnv(string(e))
+	if !ok || password == "" {
+		return "", errors.Wrapf(ErrPasswordUnavailable, "environment variable %s is not set", string(e))
+	}
+	return password, nil
+}
+
+// PasswordPrompt asks for the wallet password on the terminal, without echoing it.
+// When Confirm is set the password is asked twice and both entries must match.
+type PasswordPrompt struct {
+	Text    string
+	Confirm bool
+	in      *os.File
+	out     io.Writer
+	reader  *bufio.Reader
+}
+
+// NewPasswordPrompt creates a prompt reading from the standard input.
+func NewPasswordPrompt(text string, confirm bool) *PasswordPrompt {
+	return &PasswordPrompt{
+		Text:    text,
+		Confirm: confirm,
+		in:      os.Stdin,
+		out:     os.Stderr,
+	}
+}
+
+// WalletPassword prompts for the password.
+func (p *PasswordPrompt) WalletPassword() (string, error) {
+	password, err := p.readPassword(p.Text)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("wallet password cannot be empty")
+	}
+	if !p.Confirm {
+		return password, nil
+	}
+	confirmation, err := p.readPassword(ConfirmPasswordPromptText)
+	if err != nil {
+		return "", err
+	}
+	if confirmation != password {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+func (p *PasswordPrompt) readPassword(text string) (string, error) {
+	if _, err := fmt.Fprintf(p.out, "%s: ", text); err != nil {
+		return "", err
+	}
+	fd := int(p.in.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		// The newline typed by the user is not echoed.
+		_, _ = fmt.Fprintln(p.out)
+		if err != nil {
+			return "", errors.Wrap(err, "could not read password")
+		}
+		return string(password), nil
+	}
+	// Input is piped, read it line by line.
+	if p.reader == nil {
+		p.reader = bufio.NewReader(p.in)
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", errors.Wrap(err, "could not read password")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// PasswordSources tries each of its sources in turn and returns the first password
+// available.
+type PasswordSources []PasswordSource
+
+// WalletPassword returns the password of the first source which has one.
+func (s PasswordSources) WalletPassword() (string, error) {
+	for _, source := range s {
+		password, err := source.WalletPassword()
+		if errors.Is(err, ErrPasswordUnavailable) {
+			continue
+		}
+		return password, err
+	}
+	return "", ErrPasswordUnavailable
+}
+
+// DefaultPasswordSource for unlocking the wallet in a directory: the WALLET_PASSWORD
+// environment variable, then the walletpassword.txt file in the wallet directory, and
+// finally an interactive prompt.
+func DefaultPasswordSource(walletDir string) PasswordSource {
+	return PasswordSources{
+		EnvPassword(WalletPasswordEnvVar),
+		PasswordFile(filepath.Join(walletDir, DefaultWalletPasswordFile)),
+		NewPasswordPrompt(PasswordPromptText, false),
+	}
+}
+
+// walletPasswordFromConfig returns the configured wallet password, reading it from the
+// password source when none is set.
+func walletPasswordFromConfig(cfg *Config) (string, error) {
+	if cfg.WalletPassword != "" || cfg.PasswordSource == nil {
+		return cfg.WalletPassword, nil
+	}
+	password, err := cfg.PasswordSource.WalletPassword()
+	if err != nil {
+		return "", errors.Wrap(err, "could not read wallet password")
+	}
+	return password, nil
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func newPipedPasswordPrompt(t *testing.T, input string, confirm bool) *PasswordPrompt {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString(input)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	t.Cleanup(func() {
+		require.NoError(t, r.Close())
+	})
+	prompt := NewPasswordPrompt(PasswordPromptText, confirm)
+	prompt.in = r
+	prompt.out = new(bytes.Buffer)
+	return prompt
+}
+
+func TestPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultWalletPasswordFile)
+	_, err := PasswordFile(path).WalletPassword()
+	require.Equal(t, true, errors.Is(err, ErrPasswordUnavailable))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("Passwordz0320$\n"), 0644))
+	_, err = PasswordFile(path).WalletPassword()
+	require.ErrorContains(t, "must have 0600 permissions", err)
+
+	require.NoError(t, os.Chmod(path, 0600))
+	password, err := PasswordFile(path).WalletPassword()
+	require.NoError(t, err)
+	require.Equal(t, "Passwordz0320$", password)
+}
+
+func TestEnvPassword(t *testing.T) {
+	const name = "TEST_WALLET_PASSWORD"
+	_, err := EnvPassword(name).WalletPassword()
+	require.Equal(t, true, errors.Is(err, ErrPasswordUnavailable))
+
+	require.NoError(t, os.Setenv(name, "Passwordz0320$"))
+	defer func() {
+		require.NoError(t, os.Unsetenv(name))
+	}()
+	password, err := EnvPassword(name).WalletPassword()
+	require.NoError(t, err)
+	require.Equal(t, "Passwordz0320$", password)
+}
+
+func TestPasswordPrompt(t *testing.T) {
+	prompt := newPipedPasswordPrompt(t, "Passwordz0320$\nPasswordz0320$\n", true)
+	password, err := prompt.WalletPassword()
+	require.NoError(t, err)
+	require.Equal(t, "Passwordz0320$", password)
+	require.Equal(t, PasswordPromptText+": "+ConfirmPasswordPromptText+": ", prompt.out.(*bytes.Buffer).String())
+
+	prompt = newPipedPasswordPrompt(t, "Passwordz0320$\nother\n", true)
+	_, err = prompt.WalletPassword()
+	require.ErrorContains(t, "passwords do not match", err)
+
+	prompt = newPipedPasswordPrompt(t, "\n", false)
+	_, err = prompt.WalletPassword()
+	require.ErrorContains(t, "wallet password cannot be empty", err)
+}
+
+func TestPasswordSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultWalletPasswordFile)
+	require.NoError(t, ioutil.WriteFile(path, []byte("from file"), 0600))
+	sources := PasswordSources{
+		EnvPassword("TEST_UNSET_WALLET_PASSWORD"),
+		PasswordFile(path),
+		newPipedPasswordPrompt(t, "from prompt\n", false),
+	}
+	password, err := sources.WalletPassword()
+	require.NoError(t, err)
+	require.Equal(t, "from file", password)
+
+	_, err = PasswordSources{EnvPassword("TEST_UNSET_WALLET_PASSWORD")}.WalletPassword()
+	require.Equal(t, true, errors.Is(err, ErrPasswordUnavailable))
+}
+
+func TestOpenWallet_PasswordSource(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	path := filepath.Join(w.walletDir, DefaultWalletPasswordFile)
+	require.NoError(t, ioutil.WriteFile(path, []byte(testWalletPassword+"\n"), 0600))
+	require.NoError(t, w.Close())
+
+	opened, err := OpenWallet(ctx, &Config{
+		WalletDir:      w.walletDir,
+		PasswordSource: DefaultPasswordSource(w.walletDir),
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, opened.Close())
+	}()
+	require.Equal(t, testWalletPassword, opened.walletPassword)
+	_, err = NewImportedKeymanager(ctx, opened)
+	require.NoError(t, err)
+}
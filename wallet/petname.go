@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+)
+
+var (
+	petnameAdverbs = []string{
+		"abnormally", "absolutely", "accurately", "actively", "actually", "adequately",
+		"admittedly", "amazingly", "annually", "apparently", "barely", "basically",
+		"briefly", "broadly", "busily", "calmly", "carefully", "certainly",
+		"cheaply", "clearly", "closely", "commonly", "correctly", "curiously",
+		"daily", "deadly", "deeply", "definitely", "dearly", "directly",
+		"eagerly", "early", "easily", "equally", "especially", "evenly",
+		"exactly", "fairly", "finally", "firmly", "formally", "freely",
+		"frankly", "fully", "gently", "genuinely", "gladly", "greatly",
+		"happily", "hardly", "highly", "honestly", "hugely", "ideally",
+		"immensely", "jointly", "kindly", "largely", "lightly", "likely",
+		"literally", "lively", "loudly", "mainly",
+	}
+	petnameAdjectives = []string{
+		"able", "active", "adapted", "alert", "amazed", "amused",
+		"awake", "bold", "brave", "bright", "busy", "calm",
+		"capital", "careful", "charming", "cheerful", "clean", "clever",
+		"cool", "crisp", "curious", "daring", "decent", "eager",
+		"easy", "enabled", "epic", "equal", "exact", "fair",
+		"famous", "fast", "fine", "firm", "fit", "fond",
+		"free", "fresh", "gentle", "giving", "glad", "golden",
+		"grand", "great", "happy", "hardy", "helping", "honest",
+		"huge", "humble", "ideal", "keen", "kind", "legal",
+		"lucky", "magnetic", "modest", "moved", "neat", "noble",
+		"open", "polite", "proud", "quick",
+	}
+	petnameNames = []string{
+		"aardvark", "adder", "alpaca", "ant", "badger", "bass",
+		"bear", "beetle", "bird", "bison", "boar", "bobcat",
+		"buck", "bull", "camel", "cat", "chicken", "cicada",
+		"clam", "cobra", "colt", "condor", "cow", "coyote",
+		"crab", "crane", "cricket", "crow", "deer", "dingo",
+		"dodo", "dog", "dolphin", "dove", "duck", "eagle",
+		"eel", "elk", "emu", "falcon", "ferret", "finch",
+		"fish", "flamingo", "fly", "fox", "frog", "gecko",
+		"gnu", "goat", "goose", "gopher", "grouse", "gull",
+		"hare", "hawk", "heron", "horse", "hound", "ibex",
+		"jackal", "jaguar", "koala", "lark",
+	}
+)
+
+// petname derives a human-readable name such as "briefly-clever-heron" from a public
+// key. The name is deterministic, so an account keeps its name across processes, but
+// it is not unique: it only helps telling accounts apart at a glance.
+func petname(pubKey [48]byte) string {
+	h := sha256.Sum256(pubKey[:])
+	words := []string{
+		petnameAdverbs[binary.BigEndian.Uint64(h[0:8])%uint64(len(petnameAdverbs))],
+		petnameAdjectives[binary.BigEndian.Uint64(h[8:16])%uint64(len(petnameAdjectives))],
+		petnameNames[binary.BigEndian.Uint64(h[16:24])%uint64(len(petnameNames))],
+	}
+	return strings.Join(words, "-")
+}
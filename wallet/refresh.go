@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// Time the accounts keystore file has to stay untouched after a change on disk before
+// it is reloaded, so a burst of writes only triggers a single reload.
+const accountsReloadDebounce = 100 * time.Millisecond
+
+// accountChangesSubscription stops watching the accounts keystore file once the
+// last subscriber of a keymanager unsubscribes.
+type accountChangesSubscription struct {
+	event.Subscription
+	once    sync.Once
+	release func()
+}
+
+// Unsubscribe stops the delivery of account changes.
+func (s *accountChangesSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.Subscription.Unsubscribe()
+		s.release()
+	})
+}
+
+// startWatchingAccounts watches the accounts keystore file for as long as there are
+// subscribers to account changes.
+func (km *Keymanager) startWatchingAccounts() {
+	km.watchLock.Lock()
+	defer km.watchLock.Unlock()
+	km.watchSubscribers++
+	if km.watchSubscribers > 1 {
+		return
+	}
+	dir := filepath.Join(km.wallet.accountsPath, AccountsPath)
+	if err := fileutil.MkdirAll(dir); err != nil {
+		log.WithError(err).Error("Could not create accounts directory, not watching it for changes")
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).Error("Could not create file watcher, not watching accounts for changes")
+		return
+	}
+	// The directory is watched rather than the file, as replacing the file atomically
+	// gives it a new inode.
+	if err := watcher.Add(dir); err != nil {
+		log.WithError(err).Errorf("Could not watch %s for changes", dir)
+		if err := watcher.Close(); err != nil {
+			log.WithError(err).Debug("Could not close file watcher")
+		}
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	km.stopWatching = cancel
+	go km.listenForAccountChanges(ctx, watcher, filepath.Join(dir, AccountsKeystoreFileName))
+}
+
+// stopWatchingAccounts releases a subscriber, stopping the file watcher with the last one.
+func (km *Keymanager) stopWatchingAccounts() {
+	km.watchLock.Lock()
+	defer km.watchLock.Unlock()
+	km.watchSubscribers--
+	if km.watchSubscribers == 0 && km.stopWatching != nil {
+		km.stopWatching()
+		km.stopWatching = nil
+	}
+}
+
+// listenForAccountChanges reloads the accounts from the keystore file whenever it is
+// written, until the context is canceled.
+func (km *Keymanager) listenForAccountChanges(ctx context.Context, watcher *fsnotify.Watcher, accountsFilePath string) {
+	defer func() {
+		if err := watcher.Close(); err != nil {
+			log.WithError(err).Debug("Could not close file watcher")
+		}
+	}()
+	var reload <-chan time.Time
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Name != accountsFilePath || ev.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			reload = time.After(accountsReloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("Could not watch accounts keystore file for changes")
+		case <-reload:
+			reload = nil
+			if err := km.reloadAccountsFromKeystore(ctx); err != nil {
+				log.WithError(err).Error("Could not reload accounts from keystore file")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadAccountsFromKeystore replaces the accounts and key caches with the content of
+// the accounts keystore file, notifying subscribers if the accounts changed.
+func (km *Keymanager) reloadAccountsFromKeystore(ctx context.Context) error {
+	changed, err := km.swapAccountsFromKeystore()
+	if err != nil || !changed {
+		return err
+	}
+	pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	if err != nil {
+		return err
+	}
+	log.WithField("numAccounts", len(pubKeys)).Info("Reloaded accounts from keystore file")
+	km.accountsChangedFeed.Send(pubKeys)
+	return nil
+}
+
+// swapAccountsFromKeystore loads the accounts keystore file into the keymanager and
+// reports whether its accounts differ from the ones previously loaded.
+func (km *Keymanager) swapAccountsFromKeystore() (bool, error) {
+	km.writeLock.Lock()
+	defer km.writeLock.Unlock()
+	store, disabledPublicKeys, err := km.readAccountsKeystore()
+	if err != nil {
+		return false, err
+	}
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	prevStore, prevDisabled, prevOrdered, prevSecretKeys :=
+		km.accountsStore, km.disabledPublicKeys, km.orderedPublicKeys, km.secretKeysCache
+	km.accountsStore = store
+	km.disabledPublicKeys = disabledPublicKeys
+	if err := km.initializeKeysCachesFromKeystore(); err != nil {
+		km.accountsStore, km.disabledPublicKeys, km.orderedPublicKeys, km.secretKeysCache =
+			prevStore, prevDisabled, prevOrdered, prevSecretKeys
+		return false, errors.Wrap(err, "failed to initialize keys caches")
+	}
+	changed := !reflect.DeepEqual(prevOrdered, km.orderedPublicKeys) ||
+		!reflect.DeepEqual(prevDisabled, km.disabledPublicKeys)
+	return changed, nil
+}
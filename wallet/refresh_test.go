@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func TestKeymanager_ReloadsAccountsChangedOnDisk(t *testing.T) {
+	ctx := context.Background()
+	w := setupImportedWallet(t)
+	km, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	pubKeysChan := make(chan [][48]byte, 1)
+	sub := km.SubscribeAccountChanges(pubKeysChan)
+	defer sub.Unsubscribe()
+
+	// Another keymanager on the same wallet stands for another process.
+	other, err := NewImportedKeymanager(ctx, w)
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, other.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	select {
+	case pubKeys := <-pubKeysChan:
+		require.DeepEqual(t, [][48]byte{pubKey}, pubKeys)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for accounts changed on disk")
+	}
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+
+	require.NoError(t, other.DisableAccounts(ctx, [][48]byte{pubKey}))
+	select {
+	case pubKeys := <-pubKeysChan:
+		require.Equal(t, 0, len(pubKeys))
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for accounts changed on disk")
+	}
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.ErrorContains(t, ErrDisabledPublicKey.Error(), err)
+}
+
+func TestKeymanager_StopsWatchingWithLastSubscriber(t *testing.T) {
+	km, err := NewImportedKeymanager(context.Background(), setupImportedWallet(t))
+	require.NoError(t, err)
+	sub1 := km.SubscribeAccountChanges(make(chan [][48]byte))
+	sub2 := km.SubscribeAccountChanges(make(chan [][48]byte))
+	require.NotNil(t, km.stopWatching)
+
+	sub1.Unsubscribe()
+	// Unsubscribing twice must not release the watcher of another subscriber.
+	sub1.Unsubscribe()
+	require.NotNil(t, km.stopWatching)
+	sub2.Unsubscribe()
+	require.Equal(t, 0, km.watchSubscribers)
+	require.Equal(t, true, km.stopWatching == nil)
+}
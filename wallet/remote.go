@@ -0,0 +1,313 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RemotePublicKeysPath of the remote signer endpoint listing its public keys.
+	RemotePublicKeysPath = "/api/v1/eth2/publicKeys"
+	// RemoteSignPathPrefix of the remote signer endpoint signing with the public key appended to it.
+	RemoteSignPathPrefix = "/api/v1/eth2/sign/"
+	// Timeout applied to every request made to the remote signer.
+	remoteRequestTimeout = 10 * time.Second
+)
+
+// RemoteKeymanagerOpts for a remote keymanager. Connections to the remote signer use
+// mutual TLS unless AllowInsecure is set, in which case signing requests may be sent
+// over plain HTTP.
+type RemoteKeymanagerOpts struct {
+	RemoteCertificate *RemoteCertificateOpts `json:"remote_cert"`
+	RemoteAddr        string                 `json:"remote_address"`
+	AllowInsecure     bool                   `json:"allow_insecure,omitempty"`
+}
+
+// RemoteCertificateOpts defines the certificate authority cert, client cert and client
+// key used for mutual TLS connections to the remote signer.
+type RemoteCertificateOpts struct {
+	RequireTls     bool   `json:"require_tls"`
+	ClientCertPath string `json:"crt_path"`
+	ClientKeyPath  string `json:"key_path"`
+	CACertPath     string `json:"ca_crt_path"`
+}
+
+// remoteSignRequest is the body posted to the remote signer's sign endpoint.
+type remoteSignRequest struct {
+	SigningRoot string `json:"signingRoot"`
+}
+
+// remoteSignResponse is the body returned by the remote signer's sign endpoint.
+type remoteSignResponse struct {
+	Signature string `json:"signature"`
+}
+
+// RemoteKeymanager implementation which keeps no secrets locally and forwards
+// signing requests to a remote signer over HTTP, secured with mutual TLS.
+type RemoteKeymanager struct {
+	opts                *RemoteKeymanagerOpts
+	baseURL             string
+	client              *http.Client
+	lock                sync.RWMutex
+	orderedPublicKeys   [][48]byte
+	accountsChangedFeed *event.Feed
+	encoding            Encoding
+}
+
+// NewRemoteKeymanager instantiates a remote keymanager from the options stored in the
+// wallet's keymanageropts.json and fetches the public keys served by the remote signer.
+func NewRemoteKeymanager(ctx context.Context, wallet *Wallet) (*RemoteKeymanager, error) {
+	encoded, err := wallet.ReadKeymanagerConfigFromDisk(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read keymanager config")
+	}
+	opts := &RemoteKeymanagerOpts{}
+	if err := json.Unmarshal(encoded, opts); err != nil {
+		return nil, errors.Wrap(err, "could not decode remote keymanager config")
+	}
+	return NewRemoteKeymanagerFromOpts(ctx, opts)
+}
+
+// NewRemoteKeymanagerFromOpts instantiates a remote keymanager from connection settings.
+// The remote signer must be reached over https with mutual TLS, unless the options
+// explicitly allow insecure connections.
+func NewRemoteKeymanagerFromOpts(ctx context.Context, opts *RemoteKeymanagerOpts) (*RemoteKeymanager, error) {
+	if opts.RemoteAddr == "" {
+		return nil, errors.New("no remote signer address provided")
+	}
+	requireTLS := opts.RemoteCertificate != nil && opts.RemoteCertificate.RequireTls
+	if !requireTLS && !opts.AllowInsecure {
+		return nil, errors.New("TLS is required to connect to the remote signer unless insecure connections are allowed")
+	}
+	baseURL := strings.TrimRight(opts.RemoteAddr, "/")
+	if !strings.Contains(baseURL, "://") {
+		if requireTLS {
+			baseURL = "https://" + baseURL
+		} else {
+			baseURL = "http://" + baseURL
+		}
+	}
+	scheme := strings.ToLower(baseURL[:strings.Index(baseURL, "://")])
+	if requireTLS && scheme != "https" {
+		return nil, fmt.Errorf("remote signer address %s must use https when TLS is required", opts.RemoteAddr)
+	} else if scheme != "https" && scheme != "http" {
+		return nil, fmt.Errorf("unsupported scheme %s of remote signer address", scheme)
+	}
+	transport := &http.Transport{}
+	if requireTLS {
+		tlsCfg, err := remoteTLSConfig(opts.RemoteCertificate)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsCfg
+	} else if scheme == "http" {
+		log.WithField("remoteAddr", opts.RemoteAddr).Warn("Sending signing requests to the remote signer in cleartext")
+	}
+	km := &RemoteKeymanager{
+		opts:    opts,
+		baseURL: baseURL,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   remoteRequestTimeout,
+		},
+		accountsChangedFeed: new(event.Feed),
+	}
+	if _, err := km.FetchValidatingPublicKeys(ctx); err != nil {
+		return nil, errors.Wrap(err, "could not fetch public keys from remote signer")
+	}
+	return km, nil
+}
+
+// FetchValidatingPublicKeys lists the public keys served by the remote signer.
+// Subscribers are notified when the list differs from the previously fetched one.
+func (km *RemoteKeymanager) FetchValidatingPublicKeys(ctx context.Context) ([][48]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, km.baseURL+RemotePublicKeysPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var hexKeys []string
+	if err := km.do(req, &hexKeys); err != nil {
+		return nil, err
+	}
+	pubKeys := make([][48]byte, len(hexKeys))
+	for i, hexKey := range hexKeys {
+		pubKey, err := hex.DecodeString(strings.TrimPrefix(hexKey, "0x"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decode public key %s", hexKey)
+		}
+		if len(pubKey) != 48 {
+			return nil, fmt.Errorf("public key %s must be %d bytes", hexKey, 48)
+		}
+		pubKeys[i] = bytesutil.ToBytes48(pubKey)
+	}
+	km.lock.Lock()
+	changed := km.orderedPublicKeys != nil && !reflect.DeepEqual(km.orderedPublicKeys, pubKeys)
+	km.orderedPublicKeys = pubKeys
+	km.lock.Unlock()
+	keys := make([][48]byte, len(pubKeys))
+	copy(keys, pubKeys)
+	if changed {
+		km.accountsChangedFeed.Send(keys)
+	}
+	return keys, nil
+}
+
+// SubscribeAccountChanges creates an event subscription for a channel to listen for
+// changes of the public keys served by the remote signer, as seen by FetchValidatingPublicKeys.
+func (km *RemoteKeymanager) SubscribeAccountChanges(pubKeysChan chan [][48]byte) event.Subscription {
+	return km.accountsChangedFeed.Subscribe(pubKeysChan)
+}
+
+// SetEncoding selects the encoding of slot infos from which signing roots are
+// computed. RLPEncoding is used by default.
+func (km *RemoteKeymanager) SetEncoding(encoding Encoding) {
+	km.lock.Lock()
+	defer km.lock.Unlock()
+	km.encoding = encoding
+}
+
+// Sign forwards a signing request for the signing root of the slot info in the domain
+// to the remote signer. The public key must be one of the last fetched public keys, and
+// the returned signature is verified before it is handed out.
+func (km *RemoteKeymanager) Sign(slotInfo *SlotInfo, domain Domain, pubKey [48]byte) (bls.Signature, error) {
+	if !km.hasPublicKey(pubKey) {
+		return nil, errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	publicKey, err := bls.PublicKeyFromBytes(pubKey[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert bytes to public key")
+	}
+	signingRoot, err := km.signingRoot(slotInfo, domain)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(&remoteSignRequest{SigningRoot: fmt.Sprintf("%#x", signingRoot)})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteRequestTimeout)
+	defer cancel()
+	url := fmt.Sprintf("%s%s%#x", km.baseURL, RemoteSignPathPrefix, pubKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp := &remoteSignResponse{}
+	if err := km.do(req, resp); err != nil {
+		return nil, errors.Wrap(err, "remote signer could not sign")
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(resp.Signature, "0x"))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode signature from remote signer")
+	}
+	signature, err := bls.SignatureFromBytes(sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode signature from remote signer")
+	}
+	if !signature.Verify(publicKey, signingRoot[:]) {
+		return nil, errors.Wrapf(ErrSigFailedToVerify, "signature returned by remote signer for %#x", pubKey)
+	}
+	return signature, nil
+}
+
+// VerifySignature verifies a signature over the slot info in the domain given one of
+// the public keys served by the remote signer.
+func (km *RemoteKeymanager) VerifySignature(slotInfo *SlotInfo, domain Domain, pubKey [48]byte, signature bls.Signature) error {
+	if !km.hasPublicKey(pubKey) {
+		return errors.Wrapf(ErrUnknownPublicKey, "%#x", pubKey)
+	}
+	publicKey, err := bls.PublicKeyFromBytes(pubKey[:])
+	if err != nil {
+		return errors.Wrap(err, "could not convert bytes to public key")
+	}
+	signingRoot, err := km.signingRoot(slotInfo, domain)
+	if err != nil {
+		return err
+	}
+	if !signature.Verify(publicKey, signingRoot[:]) {
+		return ErrSigFailedToVerify
+	}
+	return nil
+}
+
+func (km *RemoteKeymanager) signingRoot(slotInfo *SlotInfo, domain Domain) ([32]byte, error) {
+	km.lock.RLock()
+	encoding := km.encoding
+	km.lock.RUnlock()
+	return ComputeSlotInfoSigningRoot(slotInfo, domain, encoding)
+}
+
+func (km *RemoteKeymanager) hasPublicKey(pubKey [48]byte) bool {
+	km.lock.RLock()
+	defer km.lock.RUnlock()
+	for _, k := range km.orderedPublicKeys {
+		if k == pubKey {
+			return true
+		}
+	}
+	return false
+}
+
+// do sends the request to the remote signer and decodes its JSON response into out.
+func (km *RemoteKeymanager) do(req *http.Request, out interface{}) error {
+	res, err := km.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.WithError(err).Debug("Could not close remote signer response body")
+		}
+	}()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Wrap(err, "could not read remote signer response")
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote signer returned status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// remoteTLSConfig loads the client key pair and certificate authority used to
+// authenticate both ends of the connection to the remote signer.
+func remoteTLSConfig(certOpts *RemoteCertificateOpts) (*tls.Config, error) {
+	if certOpts.ClientCertPath == "" || certOpts.ClientKeyPath == "" || certOpts.CACertPath == "" {
+		return nil, errors.New("client certificate, client key and CA certificate are required for TLS")
+	}
+	clientPair, err := tls.LoadX509KeyPair(certOpts.ClientCertPath, certOpts.ClientKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load client key pair")
+	}
+	caCert, err := fileutil.ReadFileAsBytes(certOpts.CACertPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read CA certificate")
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("could not add CA certificate to pool")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{clientPair},
+		RootCAs:      certPool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
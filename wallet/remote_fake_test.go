@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+)
+
+// fakeRemoteSigner is an in-process remote signer serving the endpoints consumed by
+// the remote keymanager, with its secret keys held in memory.
+type fakeRemoteSigner struct {
+	orderedPublicKeys [][48]byte
+	secretKeys        map[[48]byte]bls.SecretKey
+	// signWrongRoot makes the signer return signatures over another signing root.
+	signWrongRoot bool
+}
+
+func newFakeRemoteSigner(secretKeys []bls.SecretKey) *fakeRemoteSigner {
+	s := &fakeRemoteSigner{
+		orderedPublicKeys: make([][48]byte, len(secretKeys)),
+		secretKeys:        make(map[[48]byte]bls.SecretKey, len(secretKeys)),
+	}
+	for i, secretKey := range secretKeys {
+		pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+		s.orderedPublicKeys[i] = pubKey
+		s.secretKeys[pubKey] = secretKey
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *fakeRemoteSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == RemotePublicKeysPath:
+		hexKeys := make([]string, len(s.orderedPublicKeys))
+		for i, pubKey := range s.orderedPublicKeys {
+			hexKeys[i] = fmt.Sprintf("%#x", pubKey)
+		}
+		writeJSON(w, hexKeys)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, RemoteSignPathPrefix):
+		pubKey, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, RemoteSignPathPrefix), "0x"))
+		if err != nil || len(pubKey) != 48 {
+			http.Error(w, "invalid public key", http.StatusBadRequest)
+			return
+		}
+		secretKey, ok := s.secretKeys[bytesutil.ToBytes48(pubKey)]
+		if !ok {
+			http.Error(w, "unknown public key", http.StatusNotFound)
+			return
+		}
+		req := &remoteSignRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		signingRoot, err := hex.DecodeString(strings.TrimPrefix(req.SigningRoot, "0x"))
+		if err != nil || len(signingRoot) != 32 {
+			http.Error(w, "invalid signing root", http.StatusBadRequest)
+			return
+		}
+		if s.signWrongRoot {
+			signingRoot[0] ^= 1
+		}
+		writeJSON(w, &remoteSignResponse{
+			Signature: fmt.Sprintf("%#x", secretKey.Sign(signingRoot).Marshal()),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Error("Could not write remote signer response")
+	}
+}
@@ -0,0 +1,221 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atif-konasl/eth-research/bls"
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func generateTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// setupRemoteSigner starts a fake remote signer requiring client certificates and
+// returns the keymanager options needed to connect to it.
+func setupRemoteSigner(t *testing.T, secretKeys []bls.SecretKey) *RemoteKeymanagerOpts {
+	opts, _ := setupFakeRemoteSigner(t, secretKeys)
+	return opts
+}
+
+func setupFakeRemoteSigner(t *testing.T, secretKeys []bls.SecretKey) (*RemoteKeymanagerOpts, *fakeRemoteSigner) {
+	notAfter := time.Now().Add(time.Hour)
+	ca := generateTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	server := generateTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "remote-signer"},
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	client := generateTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "validator"},
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	serverPair, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	signer := newFakeRemoteSigner(secretKeys)
+	srv := httptest.NewUnstartedServer(signer)
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	certOpts := &RemoteCertificateOpts{
+		RequireTls:     true,
+		ClientCertPath: filepath.Join(dir, "client.crt"),
+		ClientKeyPath:  filepath.Join(dir, "client.key"),
+		CACertPath:     filepath.Join(dir, "ca.crt"),
+	}
+	require.NoError(t, ioutil.WriteFile(certOpts.ClientCertPath, client.certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(certOpts.ClientKeyPath, client.keyPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(certOpts.CACertPath, ca.certPEM, 0600))
+	return &RemoteKeymanagerOpts{
+		RemoteCertificate: certOpts,
+		RemoteAddr:        srv.Listener.Addr().String(),
+	}, signer
+}
+
+func TestRemoteKeymanager_SignAndVerify(t *testing.T) {
+	ctx := context.Background()
+	secretKey1, err := bls.RandKey()
+	require.NoError(t, err)
+	secretKey2, err := bls.RandKey()
+	require.NoError(t, err)
+	opts := setupRemoteSigner(t, []bls.SecretKey{secretKey1, secretKey2})
+
+	w, err := CreateWallet(ctx, &Config{
+		WalletDir:            filepath.Join(t.TempDir(), "wallet"),
+		KeymanagerKind:       Remote,
+		RemoteKeymanagerOpts: opts,
+	})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, w.Close())
+	}()
+	km, err := NewRemoteKeymanager(ctx, w)
+	require.NoError(t, err)
+
+	pubKeys, err := km.FetchValidatingPublicKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(pubKeys))
+	require.DeepEqual(t, secretKey2.PublicKey().Marshal(), pubKeys[1][:])
+
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	signature, err := km.Sign(slotInfo, testDomain, pubKeys[1])
+	require.NoError(t, err)
+	signingRoot := ComputeSigningRoot(slotInfo, testDomain)
+	require.DeepEqual(t, secretKey2.Sign(signingRoot[:]).Marshal(), signature.Marshal())
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKeys[1], signature))
+	require.ErrorContains(t, ErrSigFailedToVerify.Error(), km.VerifySignature(slotInfo, testDomain, pubKeys[0], signature))
+
+	_, err = km.Sign(slotInfo, testDomain, [48]byte{1})
+	require.Equal(t, true, errors.Is(err, ErrUnknownPublicKey))
+}
+
+func TestRemoteKeymanager_RequiresClientCertificate(t *testing.T) {
+	secretKey, err := bls.RandKey()
+	require.NoError(t, err)
+	opts := setupRemoteSigner(t, []bls.SecretKey{secretKey})
+
+	// Connecting without the client key pair is refused by the remote signer.
+	caCert, err := ioutil.ReadFile(opts.RemoteCertificate.CACertPath)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	require.Equal(t, true, rootCAs.AppendCertsFromPEM(caCert))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}}
+	_, err = client.Get("https://" + opts.RemoteAddr + RemotePublicKeysPath)
+	require.NotNil(t, err)
+
+	opts.RemoteCertificate.ClientKeyPath = ""
+	_, err = NewRemoteKeymanagerFromOpts(context.Background(), opts)
+	require.ErrorContains(t, "client certificate, client key and CA certificate are required", err)
+}
+
+func TestRemoteKeymanager_VerifiesRemoteSignatures(t *testing.T) {
+	secretKey, err := bls.RandKey()
+	require.NoError(t, err)
+	opts, signer := setupFakeRemoteSigner(t, []bls.SecretKey{secretKey})
+	km, err := NewRemoteKeymanagerFromOpts(context.Background(), opts)
+	require.NoError(t, err)
+
+	signer.signWrongRoot = true
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.Equal(t, true, errors.Is(err, ErrSigFailedToVerify))
+}
+
+func TestRemoteKeymanager_RequiresTLS(t *testing.T) {
+	secretKey, err := bls.RandKey()
+	require.NoError(t, err)
+	opts := setupRemoteSigner(t, []bls.SecretKey{secretKey})
+	ctx := context.Background()
+
+	// An explicit http address is refused when TLS is required.
+	httpOpts := *opts
+	httpOpts.RemoteAddr = "http://" + opts.RemoteAddr
+	_, err = NewRemoteKeymanagerFromOpts(ctx, &httpOpts)
+	require.ErrorContains(t, "must use https when TLS is required", err)
+	httpsOpts := *opts
+	httpsOpts.RemoteAddr = "https://" + opts.RemoteAddr
+	_, err = NewRemoteKeymanagerFromOpts(ctx, &httpsOpts)
+	require.NoError(t, err)
+
+	// Plain HTTP requires an explicit opt-in.
+	srv := httptest.NewServer(newFakeRemoteSigner([]bls.SecretKey{secretKey}))
+	t.Cleanup(srv.Close)
+	insecureOpts := &RemoteKeymanagerOpts{RemoteAddr: srv.URL}
+	_, err = NewRemoteKeymanagerFromOpts(ctx, insecureOpts)
+	require.ErrorContains(t, "TLS is required", err)
+	insecureOpts.AllowInsecure = true
+	km, err := NewRemoteKeymanagerFromOpts(ctx, insecureOpts)
+	require.NoError(t, err)
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	_, err = km.Sign(NewSlotInfo(2, 64, 5454), testDomain, pubKey)
+	require.NoError(t, err)
+}
+
+func TestCreateWallet_RemoteRequiresAddress(t *testing.T) {
+	_, err := CreateWallet(context.Background(), &Config{
+		WalletDir:      filepath.Join(t.TempDir(), "wallet"),
+		KeymanagerKind: Remote,
+	})
+	require.ErrorContains(t, "remote wallets require the address of a remote signer", err)
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Encoding of a slot info from which its object root is computed before signing.
+type Encoding int
+
+const (
+	// RLPEncoding hashes the RLP encoding of the slot info with keccak256, as on the Eth1 side.
+	RLPEncoding Encoding = iota
+	// SSZEncoding uses the SSZ hash tree root of the slot info, as on the Eth2 side.
+	SSZEncoding
+)
+
+// String marshals an encoding to a human readable format.
+func (e Encoding) String() string {
+	switch e {
+	case RLPEncoding:
+		return "rlp"
+	case SSZEncoding:
+		return "ssz"
+	default:
+		return fmt.Sprintf("%d", int(e))
+	}
+}
+
+// DomainType identifies the kind of message being signed, as in Eth2.
+type DomainType [4]byte
+
+// Domain a message is signed in. It mixes the domain type with the fork version and
+// genesis validators root of a network, so a signature made for one network or message
+// kind does not verify for another.
+type Domain [32]byte
+
+// DomainSlotInfo is the domain type of slot info signatures. It has the
+// DOMAIN_APPLICATION_MASK bit set so it can never collide with an Eth2 domain type.
+var DomainSlotInfo = DomainType{0x01, 0x00, 0x00, 0x01}
+
+// Hasher is implemented by messages signed by a keymanager.
+type Hasher interface {
+	Hash() common.Hash
+}
+
+// ComputeDomain returns the domain for a domain type on the network identified by its
+// fork version and genesis validators root, following the Eth2 compute_domain function.
+func ComputeDomain(domainType DomainType, forkVersion [4]byte, genesisValidatorsRoot [32]byte) Domain {
+	// hash_tree_root(ForkData(current_version, genesis_validators_root)), where the
+	// version is right-padded to a 32 bytes chunk.
+	var forkDataChunks [64]byte
+	copy(forkDataChunks[:4], forkVersion[:])
+	copy(forkDataChunks[32:], genesisValidatorsRoot[:])
+	forkDataRoot := sha256.Sum256(forkDataChunks[:])
+
+	var domain Domain
+	copy(domain[:4], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// ComputeSigningRoot returns the root actually signed for an object in a domain,
+// following the Eth2 compute_signing_root function with the object hash as its root.
+func ComputeSigningRoot(object Hasher, domain Domain) [32]byte {
+	return signingRoot(object.Hash(), domain)
+}
+
+// ComputeSlotInfoSigningRoot returns the root signed for the slot info in a domain, with
+// the object root computed using the given encoding.
+func ComputeSlotInfoSigningRoot(slotInfo *SlotInfo, domain Domain, encoding Encoding) ([32]byte, error) {
+	switch encoding {
+	case RLPEncoding:
+		return ComputeSigningRoot(slotInfo, domain), nil
+	case SSZEncoding:
+		objectRoot, err := slotInfo.HashTreeRoot()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		return signingRoot(objectRoot, domain), nil
+	default:
+		return [32]byte{}, fmt.Errorf("unsupported encoding %s", encoding)
+	}
+}
+
+// signingRoot is hash_tree_root(SigningData(object_root, domain)).
+func signingRoot(objectRoot [32]byte, domain Domain) [32]byte {
+	var signingDataChunks [64]byte
+	copy(signingDataChunks[:32], objectRoot[:])
+	copy(signingDataChunks[32:], domain[:])
+	return sha256.Sum256(signingDataChunks[:])
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/bytesutil"
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+var testDomain = ComputeDomain(DomainSlotInfo, [4]byte{}, [32]byte{})
+
+func TestComputeDomain(t *testing.T) {
+	// Eth2 mainnet deposit domain, computed with the genesis fork version and a zero
+	// genesis validators root.
+	domain := ComputeDomain(DomainType{0x03, 0x00, 0x00, 0x00}, [4]byte{}, [32]byte{})
+	require.Equal(t, "03000000f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9", hex.EncodeToString(domain[:]))
+
+	require.NotEqual(t, testDomain, ComputeDomain(DomainSlotInfo, [4]byte{1}, [32]byte{}))
+	require.NotEqual(t, testDomain, ComputeDomain(DomainSlotInfo, [4]byte{}, [32]byte{1}))
+}
+
+func TestComputeSigningRoot(t *testing.T) {
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	root := ComputeSigningRoot(slotInfo, testDomain)
+	require.Equal(t, root, ComputeSigningRoot(NewSlotInfo(2, 64, 5454), testDomain))
+	require.NotEqual(t, root, ComputeSigningRoot(NewSlotInfo(2, 64, 5455), testDomain))
+	otherNetwork := ComputeDomain(DomainSlotInfo, [4]byte{1}, [32]byte{})
+	require.NotEqual(t, root, ComputeSigningRoot(slotInfo, otherNetwork))
+}
+
+func TestKeymanager_SignatureBoundToDomain(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	signature, err := km.Sign(slotInfo, testDomain, pubKey)
+	require.NoError(t, err)
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKey, signature))
+	otherNetwork := ComputeDomain(DomainSlotInfo, [4]byte{1}, [32]byte{})
+	require.Equal(t, ErrSigFailedToVerify, km.VerifySignature(slotInfo, otherNetwork, pubKey, signature))
+}
+
+func TestKeymanager_SetEncoding(t *testing.T) {
+	ctx := context.Background()
+	km, err := NewImportedKeymanager(ctx, setupImportedWallet(t))
+	require.NoError(t, err)
+	keystore, secretKey := createRandomKeystore(t, "password")
+	require.NoError(t, km.ImportKeystores(ctx, []*Keystore{keystore}, []string{"password"}))
+	pubKey := bytesutil.ToBytes48(secretKey.PublicKey().Marshal())
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	// Signing the slot in both encodings is a double proposal.
+	km.UseSlashingProtection(nil)
+
+	rlpSignature, err := km.Sign(slotInfo, testDomain, pubKey)
+	require.NoError(t, err)
+	km.SetEncoding(SSZEncoding)
+	sszSignature, err := km.Sign(slotInfo, testDomain, pubKey)
+	require.NoError(t, err)
+
+	objectRoot, err := slotInfo.HashTreeRoot()
+	require.NoError(t, err)
+	root := signingRoot(objectRoot, testDomain)
+	require.DeepEqual(t, secretKey.Sign(root[:]).Marshal(), sszSignature.Marshal())
+	require.NoError(t, km.VerifySignature(slotInfo, testDomain, pubKey, sszSignature))
+	require.Equal(t, ErrSigFailedToVerify, km.VerifySignature(slotInfo, testDomain, pubKey, rlpSignature))
+
+	_, err = ComputeSlotInfoSigningRoot(slotInfo, testDomain, Encoding(5))
+	require.ErrorContains(t, "unsupported encoding 5", err)
+}
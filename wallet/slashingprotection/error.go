@@ -0,0 +1,15 @@
+package slashingprotection
+
+import "errors"
+
+// ErrDoubleProposal is returned when a signature is requested for a slot which the
+// public key already signed with a different signing root.
+var ErrDoubleProposal = errors.New("slot was already signed with a different signing root")
+
+// ErrSlotBelowMinimum is returned when a signature is requested for a slot lower than,
+// or equal to, the lowest slot imported from an interchange file for the public key.
+var ErrSlotBelowMinimum = errors.New("slot is not higher than the minimum imported slot")
+
+// ErrGenesisValidatorsRootMismatch is returned when importing an interchange file
+// made for a different network than the one recorded in the store.
+var ErrGenesisValidatorsRootMismatch = errors.New("genesis validators root does not match the one in the store")
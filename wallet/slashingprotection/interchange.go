@@ -0,0 +1,253 @@
+package slashingprotection
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// InterchangeFormatVersion supported by this store, as defined by EIP-3076.
+const InterchangeFormatVersion = "5"
+
+// InterchangeFormat is the EIP-3076 slashing protection interchange JSON file.
+type InterchangeFormat struct {
+	Metadata *InterchangeMetadata `json:"metadata"`
+	Data     []*ProtectionData    `json:"data"`
+}
+
+// InterchangeMetadata of an EIP-3076 interchange file.
+type InterchangeMetadata struct {
+	InterchangeFormatVersion string `json:"interchange_format_version"`
+	GenesisValidatorsRoot    string `json:"genesis_validators_root"`
+}
+
+// ProtectionData holds the signing history of a single public key.
+type ProtectionData struct {
+	Pubkey             string               `json:"pubkey"`
+	SignedBlocks       []*SignedBlock       `json:"signed_blocks"`
+	SignedAttestations []*SignedAttestation `json:"signed_attestations"`
+}
+
+// SignedBlock is a signed slot, with an optional signing root.
+type SignedBlock struct {
+	Slot        string `json:"slot"`
+	SigningRoot string `json:"signing_root,omitempty"`
+}
+
+// SignedAttestation is a signed source and target epoch pair, with an optional
+// signing root. Attestations are not signed by the keymanager and are only kept
+// so that they survive an import followed by an export.
+type SignedAttestation struct {
+	SourceEpoch string `json:"source_epoch"`
+	TargetEpoch string `json:"target_epoch"`
+	SigningRoot string `json:"signing_root,omitempty"`
+}
+
+// ImportInterchange merges the signing history of an EIP-3076 interchange file into
+// the store. Signing at or below the lowest imported slot of a public key is refused
+// from then on, unless it repeats an imported signing root.
+func (s *Store) ImportInterchange(r io.Reader) error {
+	interchange := &InterchangeFormat{}
+	if err := json.NewDecoder(r).Decode(interchange); err != nil {
+		return errors.Wrap(err, "could not decode interchange file")
+	}
+	if interchange.Metadata == nil {
+		return errors.New("interchange file has no metadata")
+	}
+	if interchange.Metadata.InterchangeFormatVersion != InterchangeFormatVersion {
+		return fmt.Errorf(
+			"unsupported interchange format version %q, expected %q",
+			interchange.Metadata.InterchangeFormatVersion,
+			InterchangeFormatVersion,
+		)
+	}
+	genesisValidatorsRoot, err := decodeRoot(interchange.Metadata.GenesisValidatorsRoot)
+	if err != nil {
+		return errors.Wrap(err, "could not decode genesis validators root")
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		metadata := tx.Bucket(metadataBucket)
+		if existing := metadata.Get(genesisValidatorsRootKey); existing != nil {
+			if !bytes.Equal(existing, genesisValidatorsRoot[:]) {
+				return errors.Wrapf(ErrGenesisValidatorsRootMismatch, "got %#x, have %#x", genesisValidatorsRoot, existing)
+			}
+		} else if err := metadata.Put(genesisValidatorsRootKey, genesisValidatorsRoot[:]); err != nil {
+			return err
+		}
+		for _, data := range interchange.Data {
+			if err := importProtectionData(tx, data); err != nil {
+				return errors.Wrapf(err, "could not import data for public key %s", data.Pubkey)
+			}
+		}
+		return nil
+	})
+}
+
+// ExportInterchange writes the full signing history of the store as an EIP-3076
+// interchange file.
+func (s *Store) ExportInterchange(w io.Writer) error {
+	interchange := &InterchangeFormat{
+		Metadata: &InterchangeMetadata{
+			InterchangeFormatVersion: InterchangeFormatVersion,
+		},
+		Data: make([]*ProtectionData, 0),
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var genesisValidatorsRoot [32]byte
+		copy(genesisValidatorsRoot[:], tx.Bucket(metadataBucket).Get(genesisValidatorsRootKey))
+		interchange.Metadata.GenesisValidatorsRoot = fmt.Sprintf("%#x", genesisValidatorsRoot)
+
+		dataByPubKey := make(map[string]*ProtectionData)
+		dataFor := func(pubKey []byte) *ProtectionData {
+			key := fmt.Sprintf("%#x", pubKey)
+			if data, ok := dataByPubKey[key]; ok {
+				return data
+			}
+			data := &ProtectionData{
+				Pubkey:             key,
+				SignedBlocks:       make([]*SignedBlock, 0),
+				SignedAttestations: make([]*SignedAttestation, 0),
+			}
+			dataByPubKey[key] = data
+			interchange.Data = append(interchange.Data, data)
+			return data
+		}
+		if err := tx.Bucket(signedSlotsBucket).ForEach(func(pubKey, _ []byte) error {
+			data := dataFor(pubKey)
+			return tx.Bucket(signedSlotsBucket).Bucket(pubKey).ForEach(func(slot, root []byte) error {
+				data.SignedBlocks = append(data.SignedBlocks, &SignedBlock{
+					Slot:        strconv.FormatUint(bytesToUint64(slot), 10),
+					SigningRoot: encodeRoot(root),
+				})
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		return tx.Bucket(signedAttestationsBucket).ForEach(func(pubKey, _ []byte) error {
+			data := dataFor(pubKey)
+			return tx.Bucket(signedAttestationsBucket).Bucket(pubKey).ForEach(func(target, value []byte) error {
+				data.SignedAttestations = append(data.SignedAttestations, &SignedAttestation{
+					SourceEpoch: strconv.FormatUint(bytesToUint64(value[:8]), 10),
+					TargetEpoch: strconv.FormatUint(bytesToUint64(target), 10),
+					SigningRoot: encodeRoot(value[8:]),
+				})
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(interchange)
+}
+
+func importProtectionData(tx *bolt.Tx, data *ProtectionData) error {
+	pubKey, err := hex.DecodeString(strings.TrimPrefix(data.Pubkey, "0x"))
+	if err != nil {
+		return err
+	}
+	if len(pubKey) != 48 {
+		return fmt.Errorf("public key must be %d bytes", 48)
+	}
+	signedSlots, err := tx.Bucket(signedSlotsBucket).CreateBucketIfNotExists(pubKey)
+	if err != nil {
+		return err
+	}
+	var minimumSlot uint64
+	for i, block := range data.SignedBlocks {
+		slot, err := strconv.ParseUint(block.Slot, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse slot %q", block.Slot)
+		}
+		root, err := decodeOptionalRoot(block.SigningRoot)
+		if err != nil {
+			return errors.Wrapf(err, "could not decode signing root of slot %d", slot)
+		}
+		if i == 0 || slot < minimumSlot {
+			minimumSlot = slot
+		}
+		if existing := signedSlots.Get(uint64ToBytes(slot)); existing != nil {
+			if !bytes.Equal(existing, root[:]) {
+				log.WithField("slot", slot).Warnf("Keeping existing signing root for public key %#x", pubKey)
+			}
+			continue
+		}
+		if err := signedSlots.Put(uint64ToBytes(slot), root[:]); err != nil {
+			return err
+		}
+	}
+	if len(data.SignedBlocks) > 0 {
+		minimums := tx.Bucket(minimumSlotsBucket)
+		// Keep the most conservative minimum across several imports.
+		if existing := minimums.Get(pubKey); existing == nil || bytesToUint64(existing) < minimumSlot {
+			if err := minimums.Put(pubKey, uint64ToBytes(minimumSlot)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(data.SignedAttestations) == 0 {
+		return nil
+	}
+	signedAttestations, err := tx.Bucket(signedAttestationsBucket).CreateBucketIfNotExists(pubKey)
+	if err != nil {
+		return err
+	}
+	for _, att := range data.SignedAttestations {
+		source, err := strconv.ParseUint(att.SourceEpoch, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse source epoch %q", att.SourceEpoch)
+		}
+		target, err := strconv.ParseUint(att.TargetEpoch, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse target epoch %q", att.TargetEpoch)
+		}
+		root, err := decodeOptionalRoot(att.SigningRoot)
+		if err != nil {
+			return errors.Wrapf(err, "could not decode signing root of target epoch %d", target)
+		}
+		if signedAttestations.Get(uint64ToBytes(target)) != nil {
+			continue
+		}
+		if err := signedAttestations.Put(uint64ToBytes(target), append(uint64ToBytes(source), root[:]...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeRoot(encoded string) ([32]byte, error) {
+	var root [32]byte
+	decoded, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return root, err
+	}
+	if len(decoded) != 32 {
+		return root, fmt.Errorf("root must be %d bytes", 32)
+	}
+	copy(root[:], decoded)
+	return root, nil
+}
+
+func decodeOptionalRoot(encoded string) ([32]byte, error) {
+	if encoded == "" {
+		return unknownSigningRoot, nil
+	}
+	return decodeRoot(encoded)
+}
+
+func encodeRoot(root []byte) string {
+	if bytes.Equal(root, unknownSigningRoot[:]) {
+		return ""
+	}
+	return fmt.Sprintf("%#x", root)
+}
@@ -0,0 +1,114 @@
+package slashingprotection
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+const testInterchange = `{
+	"metadata": {
+		"interchange_format_version": "5",
+		"genesis_validators_root": "0x04700007fabc8282644aed6d1c7c9e21d38a03a0c4ba193f3afe428824b3a673"
+	},
+	"data": [
+		{
+			"pubkey": "0xb845089a1457f811bfc000588fbb4e713669be8ce060ea6be3c6ece09afc3794106c91ca73acda5e5457122d58723bed",
+			"signed_blocks": [
+				{
+					"slot": "81952",
+					"signing_root": "0x4ff6f743a43f3b4f95350831aeaf0a122a1a392922c45d804280284a69eb850b"
+				},
+				{
+					"slot": "81951"
+				}
+			],
+			"signed_attestations": [
+				{
+					"source_epoch": "2290",
+					"target_epoch": "3007",
+					"signing_root": "0x587d6a4f59a58fe24f406e0502413e77fe1babddee641fda30034ed37ecc884d"
+				}
+			]
+		}
+	]
+}`
+
+func testInterchangePubKey(t *testing.T) [48]byte {
+	interchange := &InterchangeFormat{}
+	require.NoError(t, json.Unmarshal([]byte(testInterchange), interchange))
+	var pubKey [48]byte
+	copy(pubKey[:], mustDecodeHex(t, interchange.Data[0].Pubkey))
+	return pubKey
+}
+
+func mustDecodeHex(t *testing.T, encoded string) []byte {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestStore_ImportInterchange(t *testing.T) {
+	store := setupStore(t)
+	require.NoError(t, store.ImportInterchange(strings.NewReader(testInterchange)))
+	pubKey := testInterchangePubKey(t)
+
+	var root [32]byte
+	copy(root[:], mustDecodeHex(t, "0x4ff6f743a43f3b4f95350831aeaf0a122a1a392922c45d804280284a69eb850b"))
+	// Repeating an imported signature is allowed.
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 81952, root))
+	// Imported slots without signing root can never be signed again.
+	err := store.CheckAndRecordSlotSignature(pubKey, 81951, root)
+	require.Equal(t, true, errors.Is(err, ErrDoubleProposal))
+	// Slots at or below the lowest imported slot are refused.
+	err = store.CheckAndRecordSlotSignature(pubKey, 100, root)
+	require.Equal(t, true, errors.Is(err, ErrSlotBelowMinimum))
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 81953, root))
+}
+
+func TestStore_ImportInterchange_GenesisValidatorsRootMismatch(t *testing.T) {
+	store := setupStore(t)
+	require.NoError(t, store.ImportInterchange(strings.NewReader(testInterchange)))
+	other := strings.Replace(testInterchange, "0x04700007", "0x05700007", 1)
+	err := store.ImportInterchange(strings.NewReader(other))
+	require.Equal(t, true, errors.Is(err, ErrGenesisValidatorsRootMismatch))
+}
+
+func TestStore_ImportInterchange_UnsupportedVersion(t *testing.T) {
+	store := setupStore(t)
+	other := strings.Replace(testInterchange, `"interchange_format_version": "5"`, `"interchange_format_version": "4"`, 1)
+	err := store.ImportInterchange(strings.NewReader(other))
+	require.ErrorContains(t, "unsupported interchange format version", err)
+}
+
+func TestStore_ExportInterchange_RoundTrip(t *testing.T) {
+	store := setupStore(t)
+	require.NoError(t, store.ImportInterchange(strings.NewReader(testInterchange)))
+	pubKey := testInterchangePubKey(t)
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 81960, [32]byte{1}))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, store.ExportInterchange(buf))
+	exported := &InterchangeFormat{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), exported))
+	expected := &InterchangeFormat{}
+	require.NoError(t, json.Unmarshal([]byte(testInterchange), expected))
+	expected.Data[0].SignedBlocks = []*SignedBlock{
+		{Slot: "81951"},
+		expected.Data[0].SignedBlocks[0],
+		{Slot: "81960", SigningRoot: fmt.Sprintf("%#x", [32]byte{1})},
+	}
+	require.DeepEqual(t, expected, exported)
+
+	// The export imports cleanly into a fresh store.
+	other := setupStore(t)
+	require.NoError(t, other.ImportInterchange(buf))
+	err := other.CheckAndRecordSlotSignature(pubKey, 81960, [32]byte{2})
+	require.Equal(t, true, errors.Is(err, ErrDoubleProposal))
+}
@@ -0,0 +1,5 @@
+package slashingprotection
+
+import "github.com/sirupsen/logrus"
+
+var log = logrus.WithField("prefix", "slashingprotection")
@@ -0,0 +1,154 @@
+// Package slashingprotection defines a local, file-backed store of the signatures
+// made by a wallet's keys, refusing any signature which would be slashable, and
+// supporting the EIP-3076 slashing protection interchange format.
+package slashingprotection
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// ProtectionDbFileName of the slashing protection database inside its directory.
+	ProtectionDbFileName = "slashing_protection.db"
+	// Time to wait for the database file lock before giving up.
+	boltOpenTimeout = 1 * time.Second
+)
+
+var (
+	// Bucket holding a sub-bucket per public key, mapping signed slots to signing roots.
+	signedSlotsBucket = []byte("signed-slots")
+	// Bucket mapping public keys to the lowest slot imported from an interchange file.
+	minimumSlotsBucket = []byte("minimum-slots")
+	// Bucket holding a sub-bucket per public key, mapping attestation target epochs
+	// to their source epoch and signing root, as imported from an interchange file.
+	signedAttestationsBucket = []byte("signed-attestations")
+	// Bucket holding store wide metadata.
+	metadataBucket = []byte("metadata")
+	// Metadata key of the genesis validators root the records belong to.
+	genesisValidatorsRootKey = []byte("genesis-validators-root")
+	// Recorded in place of the signing root when an interchange file does not provide it.
+	unknownSigningRoot = [32]byte{}
+)
+
+// Store is a slashing protection database backed by a single bolt file.
+type Store struct {
+	db           *bolt.DB
+	databasePath string
+}
+
+// NewStore opens, creating it if needed, the slashing protection database in the
+// given directory.
+func NewStore(dirPath string) (*Store, error) {
+	hasDir, err := fileutil.HasDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDir {
+		if err := fileutil.MkdirAll(dirPath); err != nil {
+			return nil, errors.Wrapf(err, "could not create path: %s", dirPath)
+		}
+	}
+	datafile := filepath.Join(dirPath, ProtectionDbFileName)
+	db, err := bolt.Open(datafile, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, errors.New("cannot obtain database lock, database may be in use by another process")
+		}
+		return nil, errors.Wrap(err, "could not open slashing protection database")
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{signedSlotsBucket, minimumSlotsBucket, signedAttestationsBucket, metadataBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "could not initialize slashing protection database")
+	}
+	return &Store{db: db, databasePath: dirPath}, nil
+}
+
+// Close the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DatabasePath of the directory holding the database file.
+func (s *Store) DatabasePath() string {
+	return s.databasePath
+}
+
+// ClearDB removes the database file from disk. The store must be closed first.
+func (s *Store) ClearDB() error {
+	if _, err := os.Stat(s.databasePath); os.IsNotExist(err) {
+		return nil
+	}
+	return os.Remove(filepath.Join(s.databasePath, ProtectionDbFileName))
+}
+
+// CheckAndRecordSlotSignature checks that signing the given signing root at a slot
+// with the public key is not slashable and records it, within a single transaction.
+// Signing the very same root again is allowed, a different root for an already signed
+// slot returns ErrDoubleProposal.
+func (s *Store) CheckAndRecordSlotSignature(pubKey [48]byte, slot uint64, signingRoot [32]byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		signedSlots, err := tx.Bucket(signedSlotsBucket).CreateBucketIfNotExists(pubKey[:])
+		if err != nil {
+			return err
+		}
+		existing := signedSlots.Get(uint64ToBytes(slot))
+		if existing != nil {
+			// A zero root comes from an interchange file without signing root,
+			// which can never be proven to be a repeat of the requested signature.
+			if !bytes.Equal(existing, unknownSigningRoot[:]) && bytes.Equal(existing, signingRoot[:]) {
+				return nil
+			}
+			return errors.Wrapf(ErrDoubleProposal, "public key %#x, slot %d", pubKey, slot)
+		}
+		if minimum := tx.Bucket(minimumSlotsBucket).Get(pubKey[:]); minimum != nil && slot <= bytesToUint64(minimum) {
+			return errors.Wrapf(ErrSlotBelowMinimum, "public key %#x, slot %d", pubKey, slot)
+		}
+		return signedSlots.Put(uint64ToBytes(slot), signingRoot[:])
+	})
+}
+
+// SigningRootAtSlot returns the signing root recorded for the public key at a slot and
+// whether a signature was recorded at all. The root is zero when it is not known.
+func (s *Store) SigningRootAtSlot(pubKey [48]byte, slot uint64) ([32]byte, bool, error) {
+	var root [32]byte
+	var exists bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		signedSlots := tx.Bucket(signedSlotsBucket).Bucket(pubKey[:])
+		if signedSlots == nil {
+			return nil
+		}
+		existing := signedSlots.Get(uint64ToBytes(slot))
+		if existing == nil {
+			return nil
+		}
+		exists = true
+		copy(root[:], existing)
+		return nil
+	})
+	return root, exists, err
+}
+
+func uint64ToBytes(i uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, i)
+	return b
+}
+
+func bytesToUint64(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
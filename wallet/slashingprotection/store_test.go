@@ -0,0 +1,59 @@
+package slashingprotection
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+)
+
+func setupStore(t *testing.T) *Store {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, store.Close())
+		require.NoError(t, store.ClearDB())
+	})
+	return store
+}
+
+func TestStore_CheckAndRecordSlotSignature(t *testing.T) {
+	store := setupStore(t)
+	pubKey := [48]byte{1}
+	root := [32]byte{2}
+
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 64, root))
+	// Signing the same root twice is not slashable.
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 64, root))
+
+	err := store.CheckAndRecordSlotSignature(pubKey, 64, [32]byte{3})
+	require.Equal(t, true, errors.Is(err, ErrDoubleProposal))
+
+	// Other slots and other keys are independent.
+	require.NoError(t, store.CheckAndRecordSlotSignature(pubKey, 65, [32]byte{3}))
+	require.NoError(t, store.CheckAndRecordSlotSignature([48]byte{4}, 64, [32]byte{3}))
+
+	recorded, exists, err := store.SigningRootAtSlot(pubKey, 64)
+	require.NoError(t, err)
+	require.Equal(t, true, exists)
+	require.Equal(t, root, recorded)
+	_, exists, err = store.SigningRootAtSlot(pubKey, 66)
+	require.NoError(t, err)
+	require.Equal(t, false, exists)
+}
+
+func TestStore_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.CheckAndRecordSlotSignature([48]byte{1}, 64, [32]byte{2}))
+	require.NoError(t, store.Close())
+
+	store, err = NewStore(dir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+	err = store.CheckAndRecordSlotSignature([48]byte{1}, 64, [32]byte{3})
+	require.Equal(t, true, errors.Is(err, ErrDoubleProposal))
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 )
 
+//go:generate sszgen --path types.go --objs SlotInfo --output types.ssz.go
 type SlotInfo struct {
 	Epoch 				 uint64
 	Slot                 uint64
@@ -40,14 +41,23 @@ func rlpHash(x interface{}) (h common.Hash) {
 }
 
 // Hash returns the block hash of the header, which is simply the keccak256 hash of its
-// RLP encoding.
+// RLP encoding, the list of its epoch, slot and proposer index. Before that encoding was
+// fixed, EncodeRLP failed and every slot info hashed, and was signed, as empty input.
 func (s *SlotInfo) Hash() common.Hash {
 	return rlpHash(s)
 }
 
+// slotInfoRLP is the RLP layout of a SlotInfo. It has no RLP methods of its own,
+// so encoding and decoding it does not recurse into the ones of SlotInfo.
+type slotInfoRLP struct {
+	Epoch         uint64
+	Slot          uint64
+	ProposerIndex uint64
+}
+
 // DecodeRLP decodes the Ethereum
 func (s *SlotInfo) DecodeRLP(rlpData *rlp.Stream) error {
-	var eb SlotInfo
+	var eb slotInfoRLP
 	if err := rlpData.Decode(&eb); err != nil {
 		return err
 	}
@@ -57,7 +67,7 @@ func (s *SlotInfo) DecodeRLP(rlpData *rlp.Stream) error {
 
 // EncodeRLP serializes b into the Ethereum RLP block format.
 func (s *SlotInfo) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, SlotInfo{
+	return rlp.Encode(w, &slotInfoRLP{
 		Epoch:  		s.Epoch,
 		Slot:    		s.Slot,
 		ProposerIndex: 	s.ProposerIndex,
@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/atif-konasl/eth-research/testutil/require"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestSlotInfo_RLPRoundTrip(t *testing.T) {
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	encoded, err := rlp.EncodeToBytes(slotInfo)
+	require.NoError(t, err)
+	decoded := &SlotInfo{}
+	require.NoError(t, rlp.DecodeBytes(encoded, decoded))
+	require.DeepEqual(t, slotInfo, decoded)
+}
+
+func TestSlotInfo_RLPEncoding(t *testing.T) {
+	encoded, err := rlp.EncodeToBytes(NewSlotInfo(2, 64, 5454))
+	require.NoError(t, err)
+	require.Equal(t, "c5024082154e", hex.EncodeToString(encoded))
+	require.Equal(t, crypto.Keccak256Hash(encoded), NewSlotInfo(2, 64, 5454).Hash())
+	require.NotEqual(t, crypto.Keccak256Hash(), NewSlotInfo(2, 64, 5454).Hash())
+}
+
+func TestSlotInfo_Hash(t *testing.T) {
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	require.Equal(t, slotInfo.Hash(), NewSlotInfo(2, 64, 5454).Hash())
+	require.NotEqual(t, slotInfo.Hash(), NewSlotInfo(2, 64, 5455).Hash())
+	require.NotEqual(t, slotInfo.Hash(), NewSlotInfo(2, 65, 5454).Hash())
+}
+
+func TestSlotInfo_SSZRoundTrip(t *testing.T) {
+	slotInfo := NewSlotInfo(2, 64, 5454)
+	encoded, err := slotInfo.MarshalSSZ()
+	require.NoError(t, err)
+	require.Equal(t, "020000000000000040000000000000004e15000000000000", hex.EncodeToString(encoded))
+	decoded := &SlotInfo{}
+	require.NoError(t, decoded.UnmarshalSSZ(encoded))
+	require.DeepEqual(t, slotInfo, decoded)
+	require.ErrorContains(t, "incorrect size", decoded.UnmarshalSSZ(encoded[1:]))
+}
+
+func TestSlotInfo_HashTreeRoot(t *testing.T) {
+	root, err := NewSlotInfo(2, 64, 5454).HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, "115ae25a22ad34b44d575c7fa0f7487986230d2cdc094186151f7218e8e4d19c", hex.EncodeToString(root[:]))
+}
@@ -2,14 +2,17 @@ package wallet
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 
 	"github.com/atif-konasl/eth-research/fileutil"
+	"github.com/atif-konasl/eth-research/wallet/slashingprotection"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 // Kind defines an enum for either imported, derived, or remote-signing
@@ -28,6 +31,9 @@ const (
 const (
 	// KeymanagerConfigFileName for the keymanager used by the wallet: imported, derived, or remote.
 	KeymanagerConfigFileName = "keymanageropts.json"
+	// SlashingProtectionDirName of the directory inside the wallet directory holding the
+	// slashing protection database keymanagers check signatures against by default.
+	SlashingProtectionDirName = "slashing-protection"
 	// NewWalletPasswordPromptText for wallet creation.
 	NewWalletPasswordPromptText = "New wallet password"
 	// WalletPasswordPromptText for wallet unlocking.
@@ -50,6 +56,16 @@ type Config struct {
 	WalletDir      string
 	KeymanagerKind Kind
 	WalletPassword string
+	// PasswordSource is read for the wallet password when WalletPassword is empty.
+	PasswordSource PasswordSource
+	// RemoteKeymanagerOpts are written to keymanageropts.json when creating a remote wallet.
+	RemoteKeymanagerOpts *RemoteKeymanagerOpts
+	// KDFParams used to encrypt the keystores written by the wallet. CreateWallet saves
+	// them, DefaultKDFParams if nil, and OpenWallet uses the saved ones if nil.
+	KDFParams *KDFParams
+	// DisableSlashingProtection opts out of the slashing protection database in the
+	// wallet directory, leaving keymanagers unprotected unless given a store explicitly.
+	DisableSlashingProtection bool
 }
 
 // Wallet is a primitive in Prysm's account management which
@@ -62,6 +78,15 @@ type Wallet struct {
 	configFilePath string
 	walletPassword string
 	keymanagerKind Kind
+	kdfParams      *KDFParams
+	lockFile       *os.File
+	// passwordLock guards walletPassword. ChangePassword holds it for writing, so
+	// keymanagers never encrypt or decrypt a keystore while the password is replaced.
+	passwordLock sync.RWMutex
+	// slashingProtection is opened on first use and shared by the wallet's keymanagers.
+	disableSlashingProtection bool
+	slashingProtectionLock    sync.Mutex
+	slashingProtection        *slashingprotection.Store
 }
 
 // New creates a struct from config values.
@@ -72,7 +97,62 @@ func NewWallet(cfg *Config) *Wallet {
 		accountsPath:   accountsPath,
 		keymanagerKind: cfg.KeymanagerKind,
 		walletPassword: cfg.WalletPassword,
+		kdfParams:      cfg.KDFParams,
+
+		disableSlashingProtection: cfg.DisableSlashingProtection,
+	}
+}
+
+// SaveWallet persists the wallet's directories to disk.
+func (w *Wallet) SaveWallet() error {
+	if err := fileutil.MkdirAll(w.accountsPath); err != nil {
+		return errors.Wrap(err, "could not create wallet directory")
+	}
+	return nil
+}
+
+// WriteKeymanagerConfigToDisk takes an encoded keymanager config file
+// and writes it to the wallet path.
+func (w *Wallet) WriteKeymanagerConfigToDisk(_ context.Context, encoded []byte) error {
+	configFilePath := filepath.Join(w.accountsPath, KeymanagerConfigFileName)
+	if err := fileutil.WriteFile(configFilePath, encoded); err != nil {
+		return errors.Wrapf(err, "could not write %s", configFilePath)
+	}
+	log.WithField("configFilePath", configFilePath).Debug("Wrote keymanager config file to disk")
+	return nil
+}
+
+// ReadKeymanagerConfigFromDisk reads the encoded keymanager config file from the wallet path.
+func (w *Wallet) ReadKeymanagerConfigFromDisk(_ context.Context) ([]byte, error) {
+	configFilePath := filepath.Join(w.accountsPath, KeymanagerConfigFileName)
+	if !fileutil.FileExists(configFilePath) {
+		return nil, fmt.Errorf("no keymanager config file found at path: %s", w.accountsPath)
+	}
+	return fileutil.ReadFileAsBytes(configFilePath)
+}
+
+// WriteFileAtPath within the wallet directory given the desired path, filename, and raw data.
+// The file is replaced atomically, so a crash mid-write never leaves a truncated file behind.
+func (w *Wallet) WriteFileAtPath(_ context.Context, filePath, fileName string, data []byte) error {
+	accountPath := filepath.Join(w.accountsPath, filePath)
+	hasDir, err := fileutil.HasDir(accountPath)
+	if err != nil {
+		return err
+	}
+	if !hasDir {
+		if err := fileutil.MkdirAll(accountPath); err != nil {
+			return errors.Wrapf(err, "could not create path: %s", accountPath)
+		}
+	}
+	fullPath := filepath.Join(accountPath, fileName)
+	if err := fileutil.WriteFileAtomically(fullPath, data); err != nil {
+		return errors.Wrapf(err, "could not write %s", filePath)
 	}
+	log.WithFields(logrus.Fields{
+		"path":     fullPath,
+		"fileName": fileName,
+	}).Debug("Wrote new file at path")
+	return nil
 }
 
 // ReadFileAtPath within the wallet directory given the desired path and filename.
@@ -105,6 +185,9 @@ func (w *Wallet) ReadFileAtPath(filePath, fileName string) ([]byte, error) {
 // OpenWallet instantiates a wallet from a specified path. It checks the
 // type of keymanager associated with the wallet by reading files in the wallet
 // path, if applicable. If a wallet does not exist, returns an appropriate error.
+// The wallet password is read from the config's password source when not set.
+// The wallet directory is locked until Close is called, and ErrWalletLocked is
+// returned while another process holds the wallet open.
 func OpenWallet(_ context.Context, cfg *Config) (*Wallet, error) {
 	exists, err := Exists(cfg.WalletDir)
 	if err != nil {
@@ -130,15 +213,222 @@ func OpenWallet(_ context.Context, cfg *Config) (*Wallet, error) {
 		return nil, errors.Wrap(err, "could not read keymanager kind for wallet")
 	}
 	accountsPath := filepath.Join(cfg.WalletDir, keymanagerKind.String())
+	// Remote wallets keep no secrets, so there is nothing to unlock.
+	var walletPassword string
+	kdfParams := cfg.KDFParams
+	if keymanagerKind != Remote {
+		if walletPassword, err = walletPasswordFromConfig(cfg); err != nil {
+			return nil, err
+		}
+		saved, err := readKDFParams(accountsPath)
+		if err != nil {
+			return nil, err
+		}
+		if kdfParams == nil {
+			kdfParams = saved
+		} else if saved != nil && kdfParams.weakerThan(saved) {
+			log.WithField("walletDir", cfg.WalletDir).Warnf(
+				"Keystores will be written with weaker %s parameters than the %s ones the wallet was created with",
+				kdfParams.Function, saved.Function,
+			)
+		}
+	}
+	lockFile, err := acquireLockFile(filepath.Join(cfg.WalletDir, WalletLockFileName))
+	if err != nil {
+		return nil, err
+	}
 	return &Wallet{
 		walletDir:      cfg.WalletDir,
 		accountsPath:   accountsPath,
 		keymanagerKind: keymanagerKind,
-		walletPassword: cfg.WalletPassword,
+		walletPassword: walletPassword,
+		kdfParams:      kdfParams,
+		lockFile:       lockFile,
+
+		disableSlashingProtection: cfg.DisableSlashingProtection,
 	}, nil
 }
 
+// Close releases the lock taken on the wallet directory by OpenWallet or CreateWallet,
+// letting other processes open the wallet, and closes its slashing protection database.
+// Closing a wallet more than once is a no-op.
+func (w *Wallet) Close() error {
+	w.slashingProtectionLock.Lock()
+	store := w.slashingProtection
+	w.slashingProtection = nil
+	w.slashingProtectionLock.Unlock()
+	if store != nil {
+		if err := store.Close(); err != nil {
+			log.WithError(err).Error("Could not close slashing protection database")
+		}
+	}
+	if w.lockFile == nil {
+		return nil
+	}
+	if err := releaseLockFile(w.lockFile); err != nil {
+		return errors.Wrap(err, "could not release wallet lock")
+	}
+	w.lockFile = nil
+	return nil
+}
+
+// SlashingProtection returns the slashing protection database in the wallet directory,
+// opening it on first use. It is shared by every keymanager of the wallet and closed
+// with it. Returns nil if the wallet was opened with DisableSlashingProtection.
+func (w *Wallet) SlashingProtection() (*slashingprotection.Store, error) {
+	if w.disableSlashingProtection {
+		return nil, nil
+	}
+	w.slashingProtectionLock.Lock()
+	defer w.slashingProtectionLock.Unlock()
+	if w.slashingProtection == nil {
+		store, err := slashingprotection.NewStore(filepath.Join(w.walletDir, SlashingProtectionDirName))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not open slashing protection database")
+		}
+		w.slashingProtection = store
+	}
+	return w.slashingProtection, nil
+}
+
+// KeymanagerKind of the wallet.
+func (w *Wallet) KeymanagerKind() Kind {
+	return w.keymanagerKind
+}
+
+// CreateWallet lays out a new wallet directory for the configured keymanager kind
+// and writes its keymanager options, which for remote wallets are taken from the
+// config and for the others hold the KDF parameters. Imported wallets additionally get an
+// empty accounts keystore encrypted with the wallet password, so the result can be
+// opened with OpenWallet straight away. Returns ErrWalletExists if a wallet is
+// already present at the wallet directory. As in OpenWallet, the wallet password is
+// read from the config's password source when not set, and the wallet directory is
+// locked until Close is called.
+func CreateWallet(ctx context.Context, cfg *Config) (*Wallet, error) {
+	exists, err := Exists(cfg.WalletDir)
+	if err != nil {
+		return nil, errors.Wrap(err, CheckExistsErrMsg)
+	}
+	if exists {
+		return nil, ErrWalletExists
+	}
+	w := NewWallet(cfg)
+	if w.kdfParams == nil && cfg.KeymanagerKind != Remote {
+		w.kdfParams = DefaultKDFParams()
+	}
+	opts, err := keymanagerOptsForConfig(cfg, w.kdfParams)
+	if err != nil {
+		return nil, err
+	}
+	encodedOpts, err := json.MarshalIndent(opts, "", "\t")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal keymanager options")
+	}
+	if cfg.KeymanagerKind != Remote {
+		if w.walletPassword, err = walletPasswordFromConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	// The wallet directory is locked before anything is laid out in it, so that a failed
+	// creation can remove what it created without racing another process.
+	hadWalletDir, err := fileutil.HasDir(cfg.WalletDir)
+	if err != nil {
+		return nil, errors.Wrap(err, CheckExistsErrMsg)
+	}
+	if err := fileutil.MkdirAll(cfg.WalletDir); err != nil {
+		return nil, errors.Wrap(err, "could not create wallet directory")
+	}
+	if w.lockFile, err = acquireLockFile(filepath.Join(cfg.WalletDir, WalletLockFileName)); err != nil {
+		return nil, err
+	}
+	// Another process may have created a wallet here since the first check.
+	exists, err = Exists(cfg.WalletDir)
+	if err != nil {
+		err = errors.Wrap(err, CheckExistsErrMsg)
+	} else if exists {
+		err = ErrWalletExists
+	}
+	if err != nil {
+		if closeErr := w.Close(); closeErr != nil {
+			log.WithError(closeErr).Error("Could not release wallet lock")
+		}
+		return nil, err
+	}
+	if err := w.layOutWallet(ctx, cfg, encodedOpts); err != nil {
+		if closeErr := w.Close(); closeErr != nil {
+			log.WithError(closeErr).Error("Could not release wallet lock")
+		}
+		// Remove the partially created wallet, so that creating it can be retried.
+		created := w.accountsPath
+		if !hadWalletDir {
+			created = cfg.WalletDir
+		}
+		if removeErr := os.RemoveAll(created); removeErr != nil {
+			log.WithError(removeErr).WithField("path", created).Error("Could not remove partially created wallet")
+		}
+		return nil, err
+	}
+	log.WithFields(logrus.Fields{
+		"walletDir":      cfg.WalletDir,
+		"keymanagerKind": cfg.KeymanagerKind.String(),
+	}).Info("Created new wallet")
+	return w, nil
+}
+
+// layOutWallet creates the keymanager directory of a new wallet and initializes it.
+func (w *Wallet) layOutWallet(ctx context.Context, cfg *Config, encodedOpts []byte) error {
+	if err := w.SaveWallet(); err != nil {
+		return err
+	}
+	return w.initializeWallet(ctx, cfg, encodedOpts)
+}
+
+// initializeWallet writes the keymanager options and, for imported wallets, the empty
+// accounts keystore of a new wallet.
+func (w *Wallet) initializeWallet(ctx context.Context, cfg *Config, encodedOpts []byte) error {
+	if err := w.WriteKeymanagerConfigToDisk(ctx, encodedOpts); err != nil {
+		return errors.Wrap(err, "could not write keymanager config to disk")
+	}
+	if cfg.KeymanagerKind == Imported {
+		accountsKeystore, err := newAccountsKeystore(&accountStore{
+			PrivateKeys: [][]byte{},
+			PublicKeys:  [][]byte{},
+		}, w.walletPassword, nil, w.kdfParams)
+		if err != nil {
+			return errors.Wrap(err, "could not create accounts keystore")
+		}
+		encoded, err := json.MarshalIndent(accountsKeystore, "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := w.WriteFileAtPath(ctx, AccountsPath, AccountsKeystoreFileName, encoded); err != nil {
+			return errors.Wrap(err, "could not write accounts keystore")
+		}
+	}
+	return nil
+}
 
+// keymanagerOptsForConfig returns the options written to keymanageropts.json
+// for a freshly created wallet of the configured kind.
+func keymanagerOptsForConfig(cfg *Config, kdfParams *KDFParams) (interface{}, error) {
+	switch cfg.KeymanagerKind {
+	case Imported:
+		opts := DefaultKeymanagerOpts()
+		opts.KDFParams = kdfParams
+		return opts, nil
+	case Derived:
+		opts := DefaultDerivedKeymanagerOpts()
+		opts.KDFParams = kdfParams
+		return opts, nil
+	case Remote:
+		if cfg.RemoteKeymanagerOpts == nil || cfg.RemoteKeymanagerOpts.RemoteAddr == "" {
+			return nil, errors.New("remote wallets require the address of a remote signer")
+		}
+		return cfg.RemoteKeymanagerOpts, nil
+	default:
+		return nil, fmt.Errorf("creating a %s wallet is not supported", cfg.KeymanagerKind)
+	}
+}
 
 func readKeymanagerKindFromWalletPath(walletPath string) (Kind, error) {
 	walletItem, err := os.Open(walletPath)
@@ -162,7 +452,7 @@ func readKeymanagerKindFromWalletPath(walletPath string) (Kind, error) {
 			return keymanagerKind, nil
 		}
 	}
-	return 0, errors.New("no keymanager folder (imported, remote, derived) found in wallet path")
+	return 0, ErrNoKeymanagerFolder
 }
 
 // Exists checks if directory at walletDir exists
@@ -182,18 +472,16 @@ func Exists(walletDir string) (bool, error) {
 
 // IsValid checks if a folder contains a single key directory such as `derived`, `remote` or `imported`.
 // Returns true if one of those subdirectories exist, false otherwise.
+// Wallet.Check reports what is wrong with a wallet directory in more detail.
 func IsValid(walletDir string) (bool, error) {
 	expanded, err := fileutil.ExpandPath(walletDir)
 	if err != nil {
 		return false, err
 	}
 	f, err := os.Open(expanded)
-	if err != nil {
-		if strings.Contains(err.Error(), "no such file") ||
-			strings.Contains(err.Error(), "cannot find the file") ||
-			strings.Contains(err.Error(), "cannot find the path") {
-			return false, nil
-		}
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
 		return false, err
 	}
 	defer func() {
@@ -2,11 +2,14 @@ package wallet
 
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/atif-konasl/eth-research/fileutil"
 	"github.com/atif-konasl/eth-research/testutil/require"
 	"github.com/sirupsen/logrus"
 )
@@ -58,8 +61,104 @@ func Test_OpenWallet(t *testing.T) {
 
 	wallet, err := OpenWallet(nil, &config)
 	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, wallet.Close())
+	}()
 	require.Equal(t, "./prysm-wallet-v2", wallet.walletDir)
 	require.Equal(t, "prysm-wallet-v2/direct", wallet.accountsPath)
 	require.Equal(t, Kind(0), wallet.keymanagerKind)
-	require.Equal(t, "Lukso1105072/", wallet.walletPassword)
+	require.Equal(t, config.WalletPassword, wallet.walletPassword)
+}
+
+func Test_CreateWallet(t *testing.T) {
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	config := &Config{
+		WalletDir:      walletDir,
+		KeymanagerKind: Imported,
+		WalletPassword: "Passwordz0320$",
+	}
+
+	created, err := CreateWallet(context.Background(), config)
+	require.NoError(t, err)
+	require.NoError(t, created.Close())
+
+	valid, err := IsValid(walletDir)
+	require.NoError(t, err)
+	require.Equal(t, true, valid)
+
+	accountsFile := filepath.Join(walletDir, Imported.String(), AccountsPath, AccountsKeystoreFileName)
+	info, err := os.Stat(accountsFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	require.Equal(t, true, fileutil.FileExists(filepath.Join(walletDir, Imported.String(), KeymanagerConfigFileName)))
+
+	wallet, err := OpenWallet(context.Background(), config)
+	require.NoError(t, err)
+	require.Equal(t, Imported, wallet.keymanagerKind)
+
+	keyManager, err := NewImportedKeymanager(context.Background(), wallet)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(keyManager.accountsStore.PublicKeys))
+	require.NoError(t, wallet.Close())
+
+	_, err = CreateWallet(context.Background(), config)
+	require.ErrorContains(t, ErrWalletExists.Error(), err)
+}
+
+func Test_CreateWallet_Failed(t *testing.T) {
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	config := &Config{
+		WalletDir:      walletDir,
+		KeymanagerKind: Imported,
+		WalletPassword: "Passwordz0320$",
+		KDFParams:      &KDFParams{Function: Scrypt, N: 3, R: 8, P: 1},
+	}
+
+	// The accounts keystore cannot be encrypted after the wallet directory was laid out.
+	_, err := CreateWallet(context.Background(), config)
+	require.ErrorContains(t, "could not create accounts keystore", err)
+	hasDir, err := fileutil.HasDir(walletDir)
+	require.NoError(t, err)
+	require.Equal(t, false, hasDir)
+
+	// A directory that was already there is kept, but not the wallet laid out in it.
+	require.NoError(t, fileutil.MkdirAll(walletDir))
+	_, err = CreateWallet(context.Background(), config)
+	require.ErrorContains(t, "could not create accounts keystore", err)
+	hasDir, err = fileutil.HasDir(walletDir)
+	require.NoError(t, err)
+	require.Equal(t, true, hasDir)
+	hasDir, err = fileutil.HasDir(filepath.Join(walletDir, Imported.String()))
+	require.NoError(t, err)
+	require.Equal(t, false, hasDir)
+
+	config.KDFParams = FastKDFParams()
+	created, err := CreateWallet(context.Background(), config)
+	require.NoError(t, err)
+	require.NoError(t, created.Close())
+}
+
+func Test_CreateWallet_Derived(t *testing.T) {
+	walletDir := filepath.Join(t.TempDir(), "wallet")
+	config := &Config{
+		WalletDir:      walletDir,
+		KeymanagerKind: Derived,
+		WalletPassword: "Passwordz0320$",
+	}
+
+	wallet, err := CreateWallet(context.Background(), config)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, wallet.Close())
+	}()
+
+	encoded, err := wallet.ReadKeymanagerConfigFromDisk(context.Background())
+	require.NoError(t, err)
+	opts := &DerivedKeymanagerOpts{}
+	require.NoError(t, json.Unmarshal(encoded, opts))
+	require.Equal(t, "EIP-2334", opts.DerivedEIPNumber)
+
+	kind, err := readKeymanagerKindFromWalletPath(walletDir)
+	require.NoError(t, err)
+	require.Equal(t, Derived, kind)
 }